@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	klstr "github.com/klstr/klstr/pkg"
 	"github.com/spf13/cobra"
 )
@@ -9,20 +12,55 @@ var skipLogging bool
 var skipMetrics bool
 
 func NewAdoptCommand() *cobra.Command {
+	var (
+		components    []string
+		skip          []string
+		domain        string
+		asDefault     bool
+		proxyProtocol bool
+	)
 	cmd := &cobra.Command{
-		Use:   "adopt",
-		Short: "Adopt a new kubernetes cluster",
-		Long:  "Adopts a new kubernetes cluster by installing klstr components",
+		Use:         "adopt",
+		Short:       "Adopt a new kubernetes cluster",
+		Long:        "Adopts a new kubernetes cluster by installing klstr components. With --klstr-name the cluster is recorded as a named installation in ~/.klstr/config.yaml",
+		Annotations: map[string]string{newInstallationAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
-			adopter := klstr.NewAdopter(klstr.AdoptOptions{
+			if domain == "" && installation != nil {
+				domain = installation.Domain
+			}
+			ao := klstr.AdoptOptions{
 				KubeConfig:  kubeConfig,
+				Namespace:   clusterNamespace(),
 				SkipLogging: skipLogging,
 				SkipMetrics: skipMetrics,
-			})
-			adopter.AdoptCluster()
+				Components:  components,
+				Skip:        skip,
+				Domain:      domain,
+			}
+			if cmd.Flags().Changed("proxy-protocol") {
+				ao.ProxyProtocol = &proxyProtocol
+			}
+			adopter := klstr.NewAdopter(ao)
+			err := adopter.AdoptCluster()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if klstrName != "" {
+				err = recordInstallation(klstrName, adopter.Domain(), asDefault)
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+			}
 		},
 	}
-	cmd.Flags().BoolVar(&skipLogging, "skip-logging", false, "Do not install elastic search logging stack")
+	cmd.Flags().BoolVar(&skipLogging, "skip-logging", false, "Do not install the logging components")
 	cmd.Flags().BoolVar(&skipMetrics, "skip-metrics", false, "Do not install prometheus and grafana")
+	cmd.Flags().StringSliceVar(&components, "components", nil, "components or groups to install, along with their dependencies, --components=grafana,logging")
+	cmd.Flags().StringSliceVar(&skip, "skip", nil, "components or groups not to install, --skip=oklog")
+	cmd.Flags().StringVar(&domain, "domain", "", "base domain to publish the platform UIs under, --domain=dev.example.com")
+	cmd.Flags().BoolVar(&proxyProtocol, "proxy-protocol", false, "have the aws load balancer of the ingress pass the client addresses with the PROXY protocol")
+	cmd.Flags().BoolVar(&asDefault, "default", false, "make the installation named by --klstr-name the default one")
 	return cmd
 }
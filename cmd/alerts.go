@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	klstr "github.com/klstr/klstr/pkg"
+	"github.com/spf13/cobra"
+)
+
+func NewAlertsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alerts",
+		Short: "alerts",
+		Long:  "Manage where platform alerts are sent",
+	}
+	cmd.AddCommand(newAlertsConfigureCommand())
+	return cmd
+}
+
+// smtpPasswordEnv holds the smtp auth password when no file is given.
+const smtpPasswordEnv = "KLSTR_SMTP_PASSWORD"
+
+func newAlertsConfigureCommand() *cobra.Command {
+	var ao klstr.AlertsOptions
+	var smtpPasswordFile string
+	cmd := &cobra.Command{
+		Use:   "configure",
+		Short: "Configure alert receivers",
+		Long:  "Configures the receivers alertmanager sends the klstr alerts to, replacing the previous configuration. The smtp auth password is read from --smtp-password-file, or else from $" + smtpPasswordEnv,
+		Run: func(cmd *cobra.Command, args []string) {
+			ao.KubeConfig = kubeConfig
+			ao.Namespace = clusterNamespace()
+			smtpPassword, err := readSecret(smtpPasswordFile, smtpPasswordEnv)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			ao.SMTPPassword = smtpPassword
+			err = klstr.ConfigureAlerts(ao)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&ao.ConfigFile, "filename", "f", "", "complete alertmanager configuration to use instead of the receiver flags")
+	cmd.Flags().StringVar(&ao.SlackWebhookURL, "slack-webhook-url", "", "slack incoming webhook to post alerts to")
+	cmd.Flags().StringVar(&ao.SlackChannel, "slack-channel", "", "slack channel overriding the webhook default, --slack-channel=#alerts")
+	cmd.Flags().StringVar(&ao.EmailTo, "email-to", "", "address to email alerts to")
+	cmd.Flags().StringVar(&ao.SMTPSmarthost, "smtp-smarthost", "", "smtp server used for email alerts, --smtp-smarthost=smtp.example.com:587")
+	cmd.Flags().StringVar(&ao.SMTPFrom, "smtp-from", "", "sender address of email alerts")
+	cmd.Flags().StringVar(&ao.SMTPUsername, "smtp-username", "", "smtp auth username")
+	cmd.Flags().StringVar(&smtpPasswordFile, "smtp-password-file", "", "file holding the smtp auth password")
+	cmd.Flags().StringVar(&ao.PagerdutyServiceKey, "pagerduty-service-key", "", "pagerduty integration key to page with")
+	return cmd
+}
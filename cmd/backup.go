@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	klstr "github.com/klstr/klstr/pkg"
+	"github.com/spf13/cobra"
+)
+
+// backupSecretKeyEnv holds the secret key of the backup store when no
+// file is given.
+const backupSecretKeyEnv = "KLSTR_BACKUP_SECRET_KEY"
+
+func newDBBackupCommand() *cobra.Command {
+	var (
+		dbname        string
+		dbtype        string
+		dbiname       string
+		retentionDays int
+		timeout       time.Duration
+		keepJob       bool
+	)
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back a database up",
+		Long:  "Streams a dump of a mysql or postgres database to the backup store",
+		Run: func(cmd *cobra.Command, args []string) {
+			err := klstr.BackupDB(&klstr.DatabaseConfig{
+				DBName:        dbname,
+				DBType:        dbtype,
+				DBIName:       dbiname,
+				Namespace:     clusterNamespace(),
+				Timeout:       timeout,
+				KeepJob:       keepJob,
+				RetentionDays: retentionDays,
+			}, kubeConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&dbname, "db-name", "", "--db-name=db1")
+	cmd.Flags().StringVar(&dbtype, "type", "pg", "--type=pg/mysql")
+	cmd.Flags().StringVar(&dbiname, "instance-name", "", "--instance-name=db1")
+	cmd.Flags().IntVar(&retentionDays, "retention-days", 0, "remove the backups of the database older than this many days, 0 keeps them")
+	addDBJobFlags(cmd, &timeout, &keepJob)
+	cmd.AddCommand(newDBBackupScheduleCommand())
+	return cmd
+}
+
+func newDBBackupScheduleCommand() *cobra.Command {
+	var (
+		dbname        string
+		dbtype        string
+		dbiname       string
+		schedule      string
+		retentionDays int
+		timeout       time.Duration
+		remove        bool
+	)
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Back a database up on a schedule",
+		Long:  "Creates a cron job streaming dumps of a mysql or postgres database to the backup store",
+		Run: func(cmd *cobra.Command, args []string) {
+			dc := &klstr.DatabaseConfig{
+				DBName:        dbname,
+				DBType:        dbtype,
+				DBIName:       dbiname,
+				Namespace:     clusterNamespace(),
+				Timeout:       timeout,
+				RetentionDays: retentionDays,
+			}
+			var err error
+			if remove {
+				err = klstr.UnscheduleBackup(dc, kubeConfig)
+			} else {
+				err = klstr.ScheduleBackup(dc, schedule, kubeConfig)
+			}
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&dbname, "db-name", "", "--db-name=db1")
+	cmd.Flags().StringVar(&dbtype, "type", "pg", "--type=pg/mysql")
+	cmd.Flags().StringVar(&dbiname, "instance-name", "", "--instance-name=db1")
+	cmd.Flags().StringVar(&schedule, "schedule", "0 3 * * *", "cron expression of when to back up, in utc")
+	cmd.Flags().IntVar(&retentionDays, "retention-days", 30, "remove the backups of the database older than this many days, 0 keeps them")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Hour, "how long each backup may run")
+	cmd.Flags().BoolVar(&remove, "remove", false, "stop backing the database up on a schedule")
+	return cmd
+}
+
+func newDBBackupsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backups",
+		Short: "Manage database backups",
+		Long:  "Configure the backup store and list the backups in it",
+	}
+	cmd.AddCommand(newDBBackupsConfigureCommand())
+	cmd.AddCommand(newDBBackupsListCommand())
+	return cmd
+}
+
+func newDBBackupsConfigureCommand() *cobra.Command {
+	bs := klstr.BackupStore{}
+	var secretKeyFile string
+	cmd := &cobra.Command{
+		Use:   "configure",
+		Short: "Configure the backup store",
+		Long:  "Sets the s3 compatible bucket database backups are stored in, replacing the previous store. The secret key is read from --secret-key-file, or else from $" + backupSecretKeyEnv,
+		Run: func(cmd *cobra.Command, args []string) {
+			bs.Namespace = clusterNamespace()
+			secretKey, err := readSecret(secretKeyFile, backupSecretKeyEnv)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			bs.SecretKey = secretKey
+			err = klstr.ConfigureBackupStore(&bs, kubeConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&bs.Endpoint, "endpoint", "", "url of the s3 compatible store, --endpoint=http://minio.minio:9000")
+	cmd.Flags().StringVar(&bs.Bucket, "bucket", "", "bucket the backups are stored in")
+	cmd.Flags().StringVar(&bs.AccessKey, "access-key", "", "access key of the store")
+	cmd.Flags().StringVar(&secretKeyFile, "secret-key-file", "", "file holding the secret key of the store")
+	cmd.Flags().StringVar(&bs.Prefix, "prefix", "klstr", "prefix of the backup keys in the bucket")
+	return cmd
+}
+
+func newDBBackupsListCommand() *cobra.Command {
+	var (
+		dbname  string
+		dbtype  string
+		dbiname string
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the database backups",
+		Long:  "Lists the backups in the backup store, optionally of a type, instance or database only",
+		Run: func(cmd *cobra.Command, args []string) {
+			backups, err := klstr.ListBackups(&klstr.DatabaseConfig{
+				DBName:    dbname,
+				DBType:    dbtype,
+				DBIName:   dbiname,
+				Namespace: clusterNamespace(),
+				Timeout:   timeout,
+			}, kubeConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tINSTANCE\tDATABASE\tTAKEN\tSIZE")
+			for _, backup := range backups {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					backup.ID,
+					backup.Instance,
+					backup.Database,
+					backup.Time.Format(time.RFC3339),
+					formatSize(backup.Size),
+				)
+			}
+			w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&dbname, "db-name", "", "only list the backups of this database")
+	cmd.Flags().StringVar(&dbtype, "type", "", "only list the backups of this type, pg or mysql")
+	cmd.Flags().StringVar(&dbiname, "instance-name", "", "only list the backups of this instance")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "how long listing the backups may take")
+	return cmd
+}
+
+func newDBRestoreCommand() *cobra.Command {
+	var (
+		ro      klstr.RestoreOptions
+		dbname  string
+		dbtype  string
+		dbiname string
+		timeout time.Duration
+		keepJob bool
+	)
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a database from a backup or a dump file",
+		Long:  "Restores a backup or a local dump file into a new or existing database, replacing the objects of the dump that exist",
+		Run: func(cmd *cobra.Command, args []string) {
+			// backup ids start with the type of the backup
+			if ro.Backup != "" && !cmd.Flags().Changed("type") {
+				dbtype = strings.SplitN(ro.Backup, "/", 2)[0]
+			}
+			err := klstr.RestoreDB(&klstr.DatabaseConfig{
+				DBName:    dbname,
+				DBType:    dbtype,
+				DBIName:   dbiname,
+				Namespace: clusterNamespace(),
+				Timeout:   timeout,
+				KeepJob:   keepJob,
+			}, ro, kubeConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&ro.Backup, "backup", "", "id of the backup to restore, as listed by klstr database backups list")
+	cmd.Flags().StringVarP(&ro.DumpFile, "filename", "f", "", "local pg_dump or mysqldump file to restore")
+	cmd.Flags().StringVar(&dbname, "db-name", "", "database to restore into, defaults to the database of the backup")
+	cmd.Flags().StringVar(&dbtype, "type", "pg", "--type=pg/mysql, defaults to the type of the backup")
+	cmd.Flags().StringVar(&dbiname, "instance-name", "", "--instance-name=db1")
+	addDBJobFlags(cmd, &timeout, &keepJob)
+	return cmd
+}
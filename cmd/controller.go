@@ -11,7 +11,7 @@ func NewControllerCommand() *cobra.Command {
 		Short: "launch as a controller",
 		Long:  "launch as a controller with in cluster config",
 		Run: func(cmd *cobra.Command, args []string) {
-			err := controller.SetupController()
+			err := controller.SetupController(klstrNamespace)
 			if err != nil {
 				panic(err)
 			}
@@ -1,6 +1,13 @@
 package cmd
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
 	klstr "github.com/klstr/klstr/pkg"
 	"github.com/spf13/cobra"
 )
@@ -13,33 +20,57 @@ func NewDatabaseCommand() *cobra.Command {
 	}
 	cmd.AddCommand(newDBCreateCommand())
 	cmd.AddCommand(newDBCloneCommand())
+	cmd.AddCommand(newDBListCommand())
+	cmd.AddCommand(newDBDropCommand())
+	cmd.AddCommand(newDBBackupCommand())
+	cmd.AddCommand(newDBBackupsCommand())
+	cmd.AddCommand(newDBRestoreCommand())
 	return cmd
 }
 
 func newDBCreateCommand() *cobra.Command {
 	var (
-		dbname  string
-		dbtype  string
-		dbiname string
+		dbname          string
+		dbtype          string
+		dbiname         string
+		timeout         time.Duration
+		keepJob         bool
+		withUser        bool
+		username        string
+		userSecret      string
+		targetNamespace string
 	)
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a new database",
-		Long:  "Create a new database in mysql / postgres",
+		Long:  "Create a new database in mysql / postgres and wait for the job creating it to finish",
 		Run: func(cmd *cobra.Command, args []string) {
 			err := klstr.CreateDB(&klstr.DatabaseConfig{
-				DBName:  dbname,
-				DBType:  dbtype,
-				DBIName: dbiname,
+				DBName:          dbname,
+				DBType:          dbtype,
+				DBIName:         dbiname,
+				Namespace:       clusterNamespace(),
+				Timeout:         timeout,
+				KeepJob:         keepJob,
+				WithUser:        withUser,
+				Username:        username,
+				UserSecret:      userSecret,
+				TargetNamespace: targetNamespace,
 			}, kubeConfig)
 			if err != nil {
-				panic(err)
+				fmt.Println(err)
+				os.Exit(1)
 			}
 		},
 	}
 	cmd.Flags().StringVar(&dbname, "db-name", "", "--db-name=db1")
 	cmd.Flags().StringVar(&dbtype, "type", "pg", "--type=pg/mysql")
 	cmd.Flags().StringVar(&dbiname, "instance-name", "", "--instance-name=db1")
+	addDBJobFlags(cmd, &timeout, &keepJob)
+	cmd.Flags().BoolVar(&withUser, "with-user", false, "create a user owning the database and write its credentials to a secret")
+	cmd.Flags().StringVar(&username, "user", "", "name of the user created by --with-user, defaults to the database name")
+	cmd.Flags().StringVar(&userSecret, "secret-name", "", "secret the user credentials are written to, defaults to <db-name>-db")
+	cmd.Flags().StringVar(&targetNamespace, "target-namespace", "default", "namespace of the application the user credentials are written to")
 	return cmd
 }
 
@@ -49,6 +80,8 @@ func newDBCloneCommand() *cobra.Command {
 		todbname   string
 		dbtype     string
 		dbiname    string
+		timeout    time.Duration
+		keepJob    bool
 	)
 	cmd := &cobra.Command{
 		Use:   "clone",
@@ -56,13 +89,17 @@ func newDBCloneCommand() *cobra.Command {
 		Long:  "Clone an existing mysql or postgres database from one namespace to another",
 		Run: func(cmd *cobra.Command, args []string) {
 			err := klstr.CloneDB(&klstr.DatabaseConfig{
-				DBName:   fromdbname,
-				ToDBName: todbname,
-				DBType:   dbtype,
-				DBIName:  dbiname,
+				DBName:    fromdbname,
+				ToDBName:  todbname,
+				DBType:    dbtype,
+				DBIName:   dbiname,
+				Namespace: clusterNamespace(),
+				Timeout:   timeout,
+				KeepJob:   keepJob,
 			}, kubeConfig)
 			if err != nil {
-				panic(err)
+				fmt.Println(err)
+				os.Exit(1)
 			}
 		},
 	}
@@ -70,5 +107,130 @@ func newDBCloneCommand() *cobra.Command {
 	cmd.Flags().StringVar(&todbname, "to-db", "", "--to-db=dbname2")
 	cmd.Flags().StringVar(&dbtype, "type", "pg", "--type=pg/mysql")
 	cmd.Flags().StringVar(&dbiname, "instance-name", "", "--instance-name=db1")
+	addDBJobFlags(cmd, &timeout, &keepJob)
+	return cmd
+}
+
+func addDBJobFlags(cmd *cobra.Command, timeout *time.Duration, keepJob *bool) {
+	cmd.Flags().DurationVar(timeout, "timeout", 5*time.Minute, "how long the database job may run")
+	cmd.Flags().BoolVar(keepJob, "keep-job", false, "keep the job after it succeeded, failed jobs are always kept for a day")
+}
+
+func newDBListCommand() *cobra.Command {
+	var (
+		dbtype  string
+		dbiname string
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the databases of a db instance",
+		Long:  "Lists the databases of a mysql or postgres db instance with their owner and size",
+		Run: func(cmd *cobra.Command, args []string) {
+			databases, err := klstr.ListDBs(&klstr.DatabaseConfig{
+				DBType:    dbtype,
+				DBIName:   dbiname,
+				Namespace: clusterNamespace(),
+				Timeout:   timeout,
+			}, kubeConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tOWNER\tSIZE")
+			for _, db := range databases {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", db.Name, db.Owner, formatSize(db.Size))
+			}
+			w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&dbtype, "type", "pg", "--type=pg/mysql")
+	cmd.Flags().StringVar(&dbiname, "instance-name", "", "--instance-name=db1")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "how long the database job may run")
+	return cmd
+}
+
+func newDBDropCommand() *cobra.Command {
+	var (
+		dbname  string
+		dbtype  string
+		dbiname string
+		timeout time.Duration
+		keepJob bool
+		force   bool
+		yes     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "drop",
+		Short: "Drop a database",
+		Long:  "Drops a mysql or postgres database that is no longer referenced by a muservice or secret",
+		Run: func(cmd *cobra.Command, args []string) {
+			dc := &klstr.DatabaseConfig{
+				DBName:    dbname,
+				DBType:    dbtype,
+				DBIName:   dbiname,
+				Namespace: clusterNamespace(),
+				Timeout:   timeout,
+				KeepJob:   keepJob,
+			}
+			references, err := klstr.DatabaseReferences(dc, kubeConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if len(references) > 0 {
+				fmt.Printf("database %s is still referenced by\n", dbname)
+				for _, reference := range references {
+					fmt.Printf("- %s\n", reference)
+				}
+				if !force {
+					fmt.Println("remove the references or pass --force to drop it anyway")
+					os.Exit(1)
+				}
+			}
+			if !yes {
+				fmt.Printf("database %s on instance %s of %s will be dropped with all its data\n", dbname, dbiname, describeTarget())
+				if !confirmName("type the database name to continue: ", dbname) {
+					fmt.Println("aborted")
+					os.Exit(1)
+				}
+			}
+			err = klstr.DropDB(dc, kubeConfig)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&dbname, "db-name", "", "--db-name=db1")
+	cmd.Flags().StringVar(&dbtype, "type", "pg", "--type=pg/mysql")
+	cmd.Flags().StringVar(&dbiname, "instance-name", "", "--instance-name=db1")
+	cmd.Flags().BoolVar(&force, "force", false, "drop the database even when muservices or secrets reference it")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	addDBJobFlags(cmd, &timeout, &keepJob)
 	return cmd
 }
+
+// confirmName asks for name to be typed out, for commands losing data.
+func confirmName(prompt, name string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == name
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -30,12 +30,13 @@ func newDBIRegisterCommand() *cobra.Command {
 		Long:  "Register a mysql or postgres instance often with admin credentials",
 		Run: func(cmd *cobra.Command, args []string) {
 			err := klstr.RegisterDBInstance(&klstr.DBInstanceRegistration{
-				Name:     dbiname,
-				Host:     host,
-				Port:     port,
-				DBType:   dbtype,
-				Username: username,
-				Password: password,
+				Name:      dbiname,
+				Host:      host,
+				Port:      port,
+				DBType:    dbtype,
+				Username:  username,
+				Password:  password,
+				Namespace: clusterNamespace(),
 			}, kubeConfig)
 			if err != nil {
 				panic(err)
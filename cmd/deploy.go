@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	klstr "github.com/klstr/klstr/pkg"
+	"github.com/spf13/cobra"
+)
+
+func NewDeployCommand() *cobra.Command {
+	var (
+		file      string
+		namespace string
+		image     string
+		scale     int32
+		wait      bool
+		timeout   time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "deploy [muservice]",
+		Short: "Deploy a muservice",
+		Long:  "Create or update a muservice from a file, or change the image or scale of an existing muservice",
+		Example: `  klstr deploy -f muservice.yaml
+  klstr deploy muservice --image quay.io/repo/mysample:0.1.2
+  klstr deploy muservice --scale=3`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			do := klstr.DeployOptions{
+				KubeConfig: kubeConfig,
+				Namespace:  namespace,
+				File:       file,
+				Image:      image,
+				Wait:       wait,
+				Timeout:    timeout,
+			}
+			if len(args) > 0 {
+				do.Name = args[0]
+			}
+			if cmd.Flags().Changed("scale") {
+				do.Replicas = &scale
+			}
+			deployer, err := klstr.NewDeployer(do)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			err = deployer.Deploy()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "muservice file to create or update")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "namespace of the muservice")
+	cmd.Flags().StringVar(&image, "image", "", "--image=quay.io/repo/mysample:0.1.2")
+	cmd.Flags().Int32Var(&scale, "scale", 1, "--scale=3")
+	cmd.Flags().BoolVar(&wait, "wait", true, "wait for the rollout to finish")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for the rollout")
+	return cmd
+}
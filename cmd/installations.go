@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/klstr/klstr/pkg/config"
+	"github.com/klstr/klstr/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// newInstallationAnnotation marks the commands that may name an
+// installation missing from the klstr config, as they record it.
+const newInstallationAnnotation = "klstr.io/new-installation"
+
+// installation is the klstr installation the command targets, nil when
+// the cluster flags are used as given.
+var installation *config.Installation
+
+// namespaceResolved is set once the klstr namespace is final, either
+// picked with --klstr-namespace or looked up by clusterNamespace.
+var namespaceResolved bool
+
+// resolveInstallation fills the cluster flags that were not given from the
+// installation named by --klstr-name, or from the default installation.
+// The default installation is left out when the cluster is picked with
+// flags, so that it does not retarget the command.
+func resolveInstallation(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	namespaceResolved = flags.Changed("klstr-namespace")
+	clusterFlags := flags.Changed("kubeconfig") || flags.Changed("kube-context") || flags.Changed("klstr-namespace")
+	if klstrName == "" && clusterFlags {
+		util.SetKubeContext(kubeContext)
+		return nil
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		if klstrName != "" {
+			return err
+		}
+		util.SetKubeContext(kubeContext)
+		return nil
+	}
+	klstrConfig, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	resolved, err := klstrConfig.Resolve(klstrName)
+	if err != nil {
+		if _, ok := cmd.Annotations[newInstallationAnnotation]; !ok {
+			return err
+		}
+		resolved = nil
+	}
+	if resolved != nil {
+		if !flags.Changed("kubeconfig") {
+			kubeConfig = resolved.KubeConfig
+		}
+		if !flags.Changed("kube-context") {
+			kubeContext = resolved.Context
+		}
+		if !flags.Changed("klstr-namespace") && resolved.Namespace != "" {
+			klstrNamespace = resolved.Namespace
+		}
+		installation = resolved
+		log.Infof("Using klstr installation %s", resolved.Name)
+	}
+	util.SetKubeContext(kubeContext)
+	return nil
+}
+
+// clusterNamespace returns the klstr namespace for commands acting on the
+// cluster, targeting the namespace of older installs when none was picked.
+// Commands are not held up when the cluster cannot be reached.
+func clusterNamespace() string {
+	if namespaceResolved {
+		return klstrNamespace
+	}
+	namespaceResolved = true
+	cs, err := util.NewKubeClient(kubeConfig)
+	if err != nil {
+		return klstrNamespace
+	}
+	namespace, err := util.ResolveNamespace(cs, klstrNamespace)
+	if err != nil {
+		log.Debugf("unable to look for an older klstr install %v", err)
+		return klstrNamespace
+	}
+	klstrNamespace = namespace
+	return klstrNamespace
+}
+
+// describeTarget names the cluster and namespace a command acts on.
+func describeTarget() string {
+	context, err := util.CurrentKubeContext(kubeConfig)
+	if err != nil || context == "" {
+		context = "unknown"
+	}
+	if installation != nil {
+		return fmt.Sprintf("installation %s (context %s, namespace %s)", installation.Name, context, klstrNamespace)
+	}
+	return fmt.Sprintf("context %s, namespace %s", context, klstrNamespace)
+}
+
+// recordInstallation saves the cluster the command targeted under name.
+// The context is pinned so that switching the current context of the
+// kubeconfig does not retarget the installation.
+func recordInstallation(name, domain string, makeDefault bool) error {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	klstrConfig, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	kubeconfig := kubeConfig
+	if kubeconfig != "" {
+		kubeconfig, err = filepath.Abs(kubeconfig)
+		if err != nil {
+			return err
+		}
+	}
+	context, err := util.CurrentKubeContext(kubeConfig)
+	if err != nil {
+		return err
+	}
+	klstrConfig.Set(config.Installation{
+		Name:       name,
+		KubeConfig: kubeconfig,
+		Context:    context,
+		Namespace:  klstrNamespace,
+		Domain:     domain,
+	})
+	if makeDefault || len(klstrConfig.Installations) == 1 {
+		klstrConfig.Default = name
+	}
+	err = klstrConfig.Save(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("recorded klstr installation %s in %s\n", name, path)
+	return nil
+}
+
+// forgetInstallation removes the targeted installation from the klstr
+// config.
+func forgetInstallation() error {
+	if installation == nil {
+		return nil
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		return err
+	}
+	klstrConfig, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if !klstrConfig.Remove(installation.Name) {
+		return nil
+	}
+	err = klstrConfig.Save(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("removed klstr installation %s from %s\n", installation.Name, path)
+	return nil
+}
+
+func NewInstallationsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "installations",
+		Short: "installations",
+		Long:  "Manage the klstr installations recorded in ~/.klstr/config.yaml",
+	}
+	cmd.AddCommand(newInstallationsListCommand())
+	cmd.AddCommand(newInstallationsUseCommand())
+	cmd.AddCommand(newInstallationsRemoveCommand())
+	return cmd
+}
+
+func loadConfig() (string, *config.Config) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	klstrConfig, err := config.Load(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	return path, klstrConfig
+}
+
+func newInstallationsListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded installations",
+		Long:  "Lists the recorded klstr installations, marking the default one",
+		Run: func(cmd *cobra.Command, args []string) {
+			_, klstrConfig := loadConfig()
+			if len(klstrConfig.Installations) == 0 {
+				fmt.Println("no klstr installations recorded, run klstr adopt --klstr-name=NAME")
+				return
+			}
+			for _, i := range klstrConfig.Installations {
+				mark := " "
+				if i.Name == klstrConfig.Default {
+					mark = "*"
+				}
+				fmt.Printf("%s %s context=%s namespace=%s", mark, i.Name, i.Context, i.Namespace)
+				if i.Domain != "" {
+					fmt.Printf(" domain=%s", i.Domain)
+				}
+				fmt.Println()
+			}
+		},
+	}
+}
+
+func newInstallationsUseCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use NAME",
+		Short: "Make an installation the default",
+		Long:  "Makes commands target the named installation when --klstr-name is not given",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, klstrConfig := loadConfig()
+			_, err := klstrConfig.Resolve(args[0])
+			if err == nil {
+				klstrConfig.Default = args[0]
+				err = klstrConfig.Save(path)
+			}
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Printf("default klstr installation is now %s\n", args[0])
+		},
+	}
+}
+
+func newInstallationsRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove NAME",
+		Short: "Forget an installation",
+		Long:  "Removes an installation from the klstr config, leaving the cluster untouched",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			path, klstrConfig := loadConfig()
+			if !klstrConfig.Remove(args[0]) {
+				fmt.Printf("unknown klstr installation %s\n", args[0])
+				os.Exit(1)
+			}
+			err := klstrConfig.Save(path)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+}
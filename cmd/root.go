@@ -2,16 +2,25 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
 	"github.com/spf13/cobra"
 )
 
 var kubeConfig string
+var kubeContext string
+var klstrName string
+var manifestsDir string
+var klstrNamespace string
 
 var RootCmd = &cobra.Command{
-	Use:   "klstr",
-	Short: "klstr - friendly neighborhood kubernetes helper",
+	Use:               "klstr",
+	Short:             "klstr - friendly neighborhood kubernetes helper",
+	PersistentPreRunE: resolveInstallation,
 }
 
 func Execute() {
@@ -24,16 +33,41 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig)
 
+	RootCmd.PersistentFlags().StringVar(&klstrName, "klstr-name", "", "klstr installation from ~/.klstr/config.yaml to target, defaults to the default installation")
 	RootCmd.PersistentFlags().StringVar(&kubeConfig, "kubeconfig", "", "kubeconfig to use for interacting with klstr")
+	RootCmd.PersistentFlags().StringVar(&kubeContext, "kube-context", "", "kubeconfig context to use instead of the current one")
+	RootCmd.PersistentFlags().StringVar(&klstrNamespace, "klstr-namespace", util.DefaultNamespace, "namespace of the klstr platform components")
+	RootCmd.PersistentFlags().StringVar(&manifestsDir, "manifests-dir", "", "directory with customised copies of the bundled k8s manifests")
 
 	RootCmd.AddCommand(NewAdoptCommand())
+	RootCmd.AddCommand(NewUpgradeCommand())
+	RootCmd.AddCommand(NewUnadoptCommand())
 	RootCmd.AddCommand(NewUsersCommand())
 	RootCmd.AddCommand(NewCreateCommand())
 	RootCmd.AddCommand(NewDeleteCommand())
 	RootCmd.AddCommand(NewDBInstancesCommand())
 	RootCmd.AddCommand(NewControllerCommand())
 	RootCmd.AddCommand(NewDatabaseCommand())
+	RootCmd.AddCommand(NewDeployCommand())
+	RootCmd.AddCommand(NewStatusCommand())
+	RootCmd.AddCommand(NewAlertsCommand())
+	RootCmd.AddCommand(NewInstallationsCommand())
 }
 
 func initConfig() {
+	assets.SetManifestsDir(manifestsDir)
+}
+
+// readSecret returns the secret in file, or else in the environment
+// variable env, so that secrets stay out of the shell history and the
+// process list.
+func readSecret(file, env string) (string, error) {
+	if file == "" {
+		return os.Getenv(env), nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
 }
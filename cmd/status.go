@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	klstr "github.com/klstr/klstr/pkg"
+	"github.com/spf13/cobra"
+)
+
+func NewStatusCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of klstr components",
+		Long:  "Show the klstr client and server versions and the health of the components installed on the cluster",
+		Run: func(cmd *cobra.Command, args []string) {
+			checker, err := klstr.NewStatusChecker(klstr.StatusOptions{
+				KubeConfig: kubeConfig,
+				Namespace:  clusterNamespace(),
+			})
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			status, err := checker.Status()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			switch output {
+			case "json":
+				data, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					fmt.Println(err)
+					os.Exit(1)
+				}
+				fmt.Println(string(data))
+			case "":
+				printStatus(status)
+			default:
+				fmt.Printf("unknown output format %q\n", output)
+				os.Exit(2)
+			}
+			if !status.Adopted {
+				fmt.Fprintf(os.Stderr, "klstr is not adopted on this cluster, no components found in namespace %s, run klstr adopt first\n", klstrNamespace)
+				os.Exit(1)
+			}
+			if !status.Healthy {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "output format, one of: json")
+	return cmd
+}
+
+func printStatus(status *klstr.ClusterStatus) {
+	fmt.Printf("klstr client version %s\n", status.ClientVersion)
+	fmt.Printf("klstr server version %s\n", status.ServerVersion)
+	fmt.Println()
+	fmt.Println("components")
+	for _, c := range status.Components {
+		mark := "✅"
+		switch {
+		case !c.Installed:
+			mark = "➖"
+		case !c.Ready:
+			mark = "❌"
+		}
+		component := c.Component
+		if c.Version != "" {
+			component = fmt.Sprintf("%s %s", c.Component, c.Version)
+		}
+		fmt.Printf("- %s %s (%s)", mark, c.Name, component)
+		if c.Message != "" {
+			fmt.Printf(": %s", c.Message)
+		}
+		fmt.Println()
+	}
+}
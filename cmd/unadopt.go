@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	klstr "github.com/klstr/klstr/pkg"
+	"github.com/spf13/cobra"
+)
+
+func NewUnadoptCommand() *cobra.Command {
+	var (
+		keepData bool
+		yes      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "unadopt",
+		Short: "Remove klstr from a kubernetes cluster",
+		Long:  "Removes the components klstr installed on an adopted cluster in reverse dependency order",
+		Run: func(cmd *cobra.Command, args []string) {
+			adopter := klstr.NewAdopter(klstr.AdoptOptions{
+				KubeConfig: kubeConfig,
+				Namespace:  clusterNamespace(),
+				KeepData:   keepData,
+			})
+			plan, err := adopter.UnadoptPlan()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if len(plan) == 0 {
+				fmt.Println("no klstr components installed")
+				return
+			}
+			if !yes {
+				fmt.Printf("the following components will be removed from %s\n", describeTarget())
+				for _, component := range plan {
+					fmt.Printf("- %s\n", component.Name)
+				}
+				if !keepData {
+					fmt.Printf("along with the persistent volume claims in namespace %s\n", klstrNamespace)
+				}
+				if !confirm("continue? [y/N] ") {
+					fmt.Println("aborted")
+					os.Exit(1)
+				}
+			}
+			err = adopter.UnadoptCluster()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			err = forgetInstallation()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&keepData, "keep-data", false, "keep the persistent volume claims of logging and metrics")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
+}
+
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	klstr "github.com/klstr/klstr/pkg"
+	"github.com/spf13/cobra"
+)
+
+func NewUpgradeCommand() *cobra.Command {
+	var (
+		components []string
+		skip       []string
+		domain     string
+		timeout    time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade an adopted kubernetes cluster",
+		Long:  "Upgrades the klstr components installed on an adopted cluster to this klstr release, rolling back components that do not become ready",
+		Run: func(cmd *cobra.Command, args []string) {
+			if domain == "" && installation != nil {
+				domain = installation.Domain
+			}
+			adopter := klstr.NewAdopter(klstr.AdoptOptions{
+				KubeConfig: kubeConfig,
+				Namespace:  clusterNamespace(),
+				Components: components,
+				Skip:       skip,
+				Domain:     domain,
+				Timeout:    timeout,
+			})
+			err := adopter.UpgradeCluster()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringSliceVar(&components, "components", nil, "components or groups to upgrade, --components=grafana,logging")
+	cmd.Flags().StringSliceVar(&skip, "skip", nil, "components or groups not to upgrade, --skip=oklog")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for each upgraded component to become ready")
+	cmd.Flags().StringVar(&domain, "domain", "", "base domain to publish the platform UIs under, defaults to the domain the cluster was adopted with")
+	return cmd
+}
@@ -0,0 +1,79 @@
+//go:build ignore
+// +build ignore
+
+// gen-assets bundles the manifests under k8s/ into a go source file so that
+// the klstr binary does not depend on the working directory.
+//
+//	go run hack/gen-assets.go -src k8s -out pkg/assets/manifests.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+func main() {
+	src := flag.String("src", "k8s", "directory holding the manifests")
+	out := flag.String("out", "pkg/assets/manifests.go", "generated go file")
+	pkg := flag.String("package", "assets", "package of the generated file")
+	flag.Parse()
+
+	files := map[string][]byte{}
+	err := filepath.Walk(*src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		// keep in sync with assets.IsManifest, which is not imported so
+		// that the generator runs when the generated file is broken.
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+			return nil
+		}
+		rel, err := filepath.Rel(*src, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by hack/gen-assets.go. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", *pkg)
+	fmt.Fprintf(&buf, "var manifests = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s: %s,\n", strconv.Quote(name), strconv.Quote(string(files[name])))
+	}
+	fmt.Fprintf(&buf, "}\n")
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile(*out, source, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,16 @@
+#!/usr/bin/env bash
+
+# Regenerates deepcopy functions, the typed clientset, listers and informers
+# for the klstr API group. Expects k8s.io/code-generator to be vendored.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(dirname ${BASH_SOURCE})/..
+CODEGEN_PKG=${CODEGEN_PKG:-$(cd ${SCRIPT_ROOT}; ls -d -1 ./vendor/k8s.io/code-generator 2>/dev/null || echo ${GOPATH}/src/k8s.io/code-generator)}
+
+${CODEGEN_PKG}/generate-groups.sh "deepcopy,client,informer,lister" \
+  github.com/klstr/klstr/pkg/client github.com/klstr/klstr/pkg/apis \
+  klstr:v1 \
+  --go-header-file ${SCRIPT_ROOT}/hack/boilerplate.go.txt
@@ -1,36 +1,82 @@
 package klstr
 
 import (
-	"os"
+	"fmt"
+	"strings"
+	"time"
 
 	prometheusop "github.com/coreos/prometheus-operator/pkg/client/monitoring"
 	prometheusopv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	clientset "github.com/klstr/klstr/pkg/client/clientset/versioned"
 	"github.com/klstr/klstr/pkg/manifests"
+	"github.com/klstr/klstr/pkg/util"
+	"github.com/klstr/klstr/pkg/version"
 	log "github.com/sirupsen/logrus"
+	apiextnclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 type AdoptOptions struct {
-	KubeConfig  string
-	SkipLogging bool
-	SkipMetrics bool
+	KubeConfig string
+	// Namespace is where the platform components are installed.
+	Namespace string
+	// Domain is the base domain platform UIs are published under. The
+	// domain recorded in the release is used when it is empty.
+	Domain string
+	// ProxyProtocol has the ingress load balancer pass the client
+	// addresses with the PROXY protocol. The setting recorded in the
+	// release is kept when it is nil.
+	ProxyProtocol *bool
+	SkipLogging   bool
+	SkipMetrics   bool
+	// Components limits the install to the named components or groups
+	// and their dependencies. Everything is installed when it is empty.
+	Components []string
+	// Skip names components or groups that are not installed.
+	Skip []string
+	// Timeout bounds the wait for an upgraded component to become ready.
+	Timeout time.Duration
+	// KeepData keeps the persistent volume claims of components when the
+	// cluster is unadopted.
+	KeepData bool
 }
 type Adopter struct {
 	ao         AdoptOptions
 	clientSet  *kubernetes.Clientset
 	pclientSet *prometheusop.Clientset
+	eclientSet *apiextnclient.Clientset
+	kclientSet *clientset.Clientset
+	applier    *manifests.Applier
 }
 
-type ServiceInstaller interface {
-	InstallService() error
+const (
+	ComponentInstalled  = "installed"
+	ComponentUpgraded   = "upgraded"
+	ComponentRolledBack = "rolled back"
+	ComponentRemoved    = "removed"
+	ComponentFailed     = "failed"
+	ComponentSkipped    = "skipped"
+)
+
+type ComponentResult struct {
+	Name    string
+	Version string
+	Result  string
+	Err     error
+	// Endpoints are the hosts the component is published at.
+	Endpoints []string
+	// Kept are the objects of the component left in place on removal as
+	// klstr does not manage them.
+	Kept []string
 }
 
 func NewAdopter(ao AdoptOptions) *Adopter {
-	if ao.KubeConfig == "" {
-		ao.KubeConfig = os.Getenv("KUBECONFIG")
+	if ao.Namespace == "" {
+		ao.Namespace = util.DefaultNamespace
 	}
-	config, err := clientcmd.BuildConfigFromFlags("", ao.KubeConfig)
+	config, err := util.NewClientConfig(ao.KubeConfig)
 	if err != nil {
 		log.Errorf("Unable to setup client config - %s", err.Error())
 		panic(err)
@@ -49,28 +95,230 @@ func NewAdopter(ao AdoptOptions) *Adopter {
 		log.Errorf("Unable to create prometheus operator client from config - %s", err.Error())
 		panic(err)
 	}
+	eclientSet, err := apiextnclient.NewForConfig(config)
+	if err != nil {
+		log.Errorf("Unable to create apiextensions client from config - %s", err.Error())
+		panic(err)
+	}
+	kclientSet, err := clientset.NewForConfig(config)
+	if err != nil {
+		log.Errorf("Unable to create klstr client from config - %s", err.Error())
+		panic(err)
+	}
+	applier, err := manifests.NewApplier(config)
+	if err != nil {
+		log.Errorf("Unable to create applier from config - %s", err.Error())
+		panic(err)
+	}
 	adopter := &Adopter{
 		ao:         ao,
 		clientSet:  clientSet,
 		pclientSet: pclientSet,
+		eclientSet: eclientSet,
+		kclientSet: kclientSet,
+		applier:    applier,
 	}
 	return adopter
 }
 
-func (a *Adopter) AdoptCluster() {
-	err := manifests.NewGrafanaInstaller(a.clientSet).InstallService()
+// AdoptCluster installs the selected components in dependency order. A
+// failing component does not stop the install, but the components that
+// depend on it are skipped. The installed components are recorded as the
+// release of the cluster.
+func (a *Adopter) AdoptCluster() error {
+	skip := append([]string{}, a.ao.Skip...)
+	if a.ao.SkipLogging {
+		skip = append(skip, manifests.GroupLogging)
+	}
+	if a.ao.SkipMetrics {
+		skip = append(skip, manifests.GroupMetrics)
+	}
+	components, err := manifests.ResolveComponents(a.ao.Components, skip)
+	if err != nil {
+		return err
+	}
+	err = util.EnsureNamespace(a.clientSet, a.ao.Namespace)
+	if err != nil {
+		return err
+	}
+	release, err := manifests.GetRelease(a.clientSet, a.ao.Namespace)
+	if err != nil {
+		return err
+	}
+	err = a.resolveDomain(release)
+	if err != nil {
+		return err
+	}
+	options := a.componentOptions()
+	results := runComponents(components, func(component manifests.Component) ComponentResult {
+		log.Infof("Installing component %s", component.Name)
+		installer := component.Installer(options)
+		result := ComponentResult{Name: component.Name}
+		objects, err := installer.Objects()
+		if err == nil {
+			result.Version = manifests.ManifestVersion(objects)
+			result.Endpoints = manifests.IngressHosts(objects)
+			err = installer.InstallService()
+		}
+		if err != nil {
+			log.Errorf("Unable to install %s - %s", component.Name, err)
+			result.Result = ComponentFailed
+			result.Err = err
+			return result
+		}
+		result.Result = ComponentInstalled
+		return result
+	})
+	printComponentResults(results)
+	a.printIngressAddress()
+	err = a.saveRelease(results)
 	if err != nil {
-		log.Errorf("Unable to install grafana - %s", err)
+		return err
+	}
+	return componentErrors("install", results)
+}
+
+// Domain returns the base domain platform UIs are published under, once
+// the cluster is adopted.
+func (a *Adopter) Domain() string {
+	return a.ao.Domain
+}
+
+// resolveDomain validates the base domain platform UIs are published
+// under, falling back to the domain the release was installed with. The
+// proxy protocol setting of the release is kept as well unless given.
+func (a *Adopter) resolveDomain(release *manifests.Release) error {
+	if a.ao.Domain == "" && release != nil {
+		a.ao.Domain = release.Domain
+	}
+	if a.ao.ProxyProtocol == nil {
+		proxyProtocol := release != nil && release.ProxyProtocol
+		a.ao.ProxyProtocol = &proxyProtocol
+	}
+	a.ao.Domain = strings.ToLower(strings.Trim(a.ao.Domain, "."))
+	if a.ao.Domain == "" {
+		return nil
+	}
+	errs := validation.IsDNS1123Subdomain(a.ao.Domain)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid domain %s: %s", a.ao.Domain, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// printIngressAddress tells where the published hosts have to point to
+// reach the ingress controller.
+func (a *Adopter) printIngressAddress() {
+	if a.ao.Domain == "" {
+		return
+	}
+	address, err := manifests.IngressAddress(a.clientSet, a.ao.Namespace)
+	if errors.IsNotFound(err) {
 		return
 	}
-	err = manifests.NewOkLogInstaller(a.clientSet).InstallService()
 	if err != nil {
-		log.Errorf("Unable to install oklog - %s", err)
-		panic(err)
+		log.Errorf("Unable to get the ingress address - %s", err)
+		return
+	}
+	if address == "" {
+		fmt.Printf("the ingress load balancer has no address yet, point *.%s at the external address of service %s/%s once it is assigned\n", a.ao.Domain, a.ao.Namespace, manifests.NginxServiceName)
+		return
 	}
-	err = manifests.NewPrometheusOperatorInstaller(a.clientSet, a.pclientSet).InstallService()
+	fmt.Printf("point *.%s at %s\n", a.ao.Domain, address)
+}
+
+func (a *Adopter) componentOptions() manifests.ComponentOptions {
+	return manifests.ComponentOptions{
+		Namespace:        a.ao.Namespace,
+		Domain:           a.ao.Domain,
+		ProxyProtocol:    a.ao.ProxyProtocol != nil && *a.ao.ProxyProtocol,
+		KubeClient:       a.clientSet,
+		PrometheusClient: a.pclientSet,
+		ExtensionsClient: a.eclientSet,
+		Applier:          a.applier,
+	}
+}
+
+// saveRelease records the version of every component installed or
+// upgraded successfully.
+func (a *Adopter) saveRelease(results []ComponentResult) error {
+	release, err := manifests.GetRelease(a.clientSet, a.ao.Namespace)
 	if err != nil {
-		log.Errorf("Unable to install prometheus operator - %s", err)
-		panic(err)
+		return err
+	}
+	if release == nil {
+		release = &manifests.Release{Components: map[string]string{}}
+	}
+	release.Version = version.Version
+	release.Domain = a.ao.Domain
+	release.ProxyProtocol = a.ao.ProxyProtocol != nil && *a.ao.ProxyProtocol
+	for _, result := range results {
+		if result.Err == nil {
+			release.Components[result.Name] = result.Version
+		}
+	}
+	return manifests.SaveRelease(a.clientSet, a.ao.Namespace, release)
+}
+
+// runComponents runs install for every component in order. Components
+// depending on a component that did not succeed are skipped.
+func runComponents(
+	components []manifests.Component,
+	install func(component manifests.Component) ComponentResult,
+) []ComponentResult {
+	var results []ComponentResult
+	failed := map[string]bool{}
+	for _, component := range components {
+		var blocked []string
+		for _, dep := range component.Dependencies {
+			if failed[dep] {
+				blocked = append(blocked, dep)
+			}
+		}
+		if len(blocked) > 0 {
+			failed[component.Name] = true
+			results = append(results, ComponentResult{
+				Name:   component.Name,
+				Result: ComponentSkipped,
+				Err:    fmt.Errorf("depends on %s", strings.Join(blocked, ", ")),
+			})
+			continue
+		}
+		result := install(component)
+		if result.Err != nil {
+			failed[component.Name] = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func componentErrors(action string, results []ComponentResult) error {
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to %s components: %s", action, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func printComponentResults(results []ComponentResult) {
+	fmt.Println("components")
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("- %s %s: %s\n", result.Name, result.Result, result.Err)
+			continue
+		}
+		fmt.Printf("- %s %s %s\n", result.Name, result.Version, result.Result)
+		for _, endpoint := range result.Endpoints {
+			fmt.Printf("  http://%s\n", endpoint)
+		}
+		for _, kept := range result.Kept {
+			fmt.Printf("  kept %s, not managed by klstr\n", kept)
+		}
 	}
 }
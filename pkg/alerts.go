@@ -0,0 +1,114 @@
+package klstr
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/klstr/klstr/pkg/manifests"
+	"github.com/klstr/klstr/pkg/util"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AlertsOptions configure where alertmanager sends alerts. Either
+// ConfigFile or at least one receiver has to be set.
+type AlertsOptions struct {
+	KubeConfig string
+	Namespace  string
+	// ConfigFile is a complete alertmanager configuration, used as is.
+	ConfigFile          string
+	SlackWebhookURL     string
+	SlackChannel        string
+	EmailTo             string
+	SMTPSmarthost       string
+	SMTPFrom            string
+	SMTPUsername        string
+	SMTPPassword        string
+	PagerdutyServiceKey string
+}
+
+// alertsReceiver is the receiver every alert is routed to.
+const alertsReceiver = "klstr"
+
+// ConfigureAlerts writes the alertmanager configuration. The operator
+// reloads alertmanager once the secret changes.
+func ConfigureAlerts(ao AlertsOptions) error {
+	if ao.Namespace == "" {
+		ao.Namespace = util.DefaultNamespace
+	}
+	config, err := alertmanagerConfig(ao)
+	if err != nil {
+		return err
+	}
+	err = manifests.ValidateAlertmanagerConfig(config)
+	if err != nil {
+		return err
+	}
+	secret, err := manifests.AlertmanagerConfigSecretFor(config)
+	if err != nil {
+		return err
+	}
+	restConfig, err := util.NewClientConfig(ao.KubeConfig)
+	if err != nil {
+		return err
+	}
+	cs, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	release, err := manifests.GetRelease(cs, ao.Namespace)
+	if err != nil {
+		return err
+	}
+	if release == nil || release.Components[manifests.AlertmanagerComponent] == "" {
+		return fmt.Errorf("alertmanager is not installed in namespace %s, run klstr adopt --components=%s first", ao.Namespace, manifests.AlertmanagerComponent)
+	}
+	applier, err := manifests.NewApplier(restConfig)
+	if err != nil {
+		return err
+	}
+	applier = applier.WithLabels(map[string]string{manifests.ComponentLabel: manifests.AlertmanagerComponent})
+	result, err := applier.Apply(ao.Namespace, secret)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("alertmanager configuration %s\n", result)
+	return nil
+}
+
+func alertmanagerConfig(ao AlertsOptions) ([]byte, error) {
+	if ao.ConfigFile != "" {
+		return ioutil.ReadFile(ao.ConfigFile)
+	}
+	receiver := manifests.AlertmanagerReceiver{Name: alertsReceiver}
+	if ao.SlackWebhookURL != "" {
+		receiver.SlackConfigs = append(receiver.SlackConfigs, manifests.SlackConfig{
+			APIURL:       ao.SlackWebhookURL,
+			Channel:      ao.SlackChannel,
+			SendResolved: true,
+		})
+	}
+	if ao.EmailTo != "" {
+		if ao.SMTPSmarthost == "" || ao.SMTPFrom == "" {
+			return nil, errors.New("email alerts need an smtp smarthost and from address")
+		}
+		receiver.EmailConfigs = append(receiver.EmailConfigs, manifests.EmailConfig{
+			To:           ao.EmailTo,
+			SendResolved: true,
+		})
+	}
+	if ao.PagerdutyServiceKey != "" {
+		receiver.PagerdutyConfigs = append(receiver.PagerdutyConfigs, manifests.PagerdutyConfig{
+			ServiceKey: ao.PagerdutyServiceKey,
+		})
+	}
+	if len(receiver.SlackConfigs)+len(receiver.EmailConfigs)+len(receiver.PagerdutyConfigs) == 0 {
+		return nil, errors.New("no receiver given, set a slack webhook, an email address, a pagerduty key or a config file")
+	}
+	config := manifests.NewAlertmanagerConfig(receiver)
+	config.Global.SMTPSmarthost = ao.SMTPSmarthost
+	config.Global.SMTPFrom = ao.SMTPFrom
+	config.Global.SMTPAuthUsername = ao.SMTPUsername
+	config.Global.SMTPAuthPassword = ao.SMTPPassword
+	return config.Marshal()
+}
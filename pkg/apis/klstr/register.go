@@ -0,0 +1,3 @@
+package klstr
+
+const GroupName = "io.klstr"
@@ -0,0 +1,6 @@
+// +k8s:deepcopy-gen=package
+// +groupName=io.klstr
+// +groupGoName=Klstr
+
+// Package v1 is the v1 version of the klstr API.
+package v1
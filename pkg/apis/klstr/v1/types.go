@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Muservice describes a micro service along with the backing services
+// and databases it depends on.
+type Muservice struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MuserviceSpec   `json:"spec"`
+	Status MuserviceStatus `json:"status,omitempty"`
+}
+
+type MuserviceSpec struct {
+	Image       string           `json:"image"`
+	Replicas    *int32           `json:"replicas,omitempty"`
+	Ports       []MuservicePort  `json:"ports,omitempty"`
+	Expose      Expose           `json:"expose,omitempty"`
+	Environment []corev1.EnvVar  `json:"environment,omitempty"`
+	Services    []BackingService `json:"services,omitempty"`
+	Databases   []Database       `json:"databases,omitempty"`
+}
+
+type MuservicePort struct {
+	Name     string          `json:"name,omitempty"`
+	Port     int32           `json:"port"`
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+}
+
+// Expose lists the hosts and paths on which a Muservice is reachable from
+// outside the cluster. It can be written either as a single host name or
+// as a list of rules.
+type Expose []ExposeRule
+
+type ExposeRule struct {
+	Host  string   `json:"host"`
+	Paths []string `json:"paths,omitempty"`
+	// Port is the service port traffic is routed to. It defaults to the
+	// first port of the Muservice.
+	Port int32 `json:"port,omitempty"`
+}
+
+func (e *Expose) UnmarshalJSON(data []byte) error {
+	var host string
+	if err := json.Unmarshal(data, &host); err == nil {
+		*e = nil
+		if host != "" {
+			*e = Expose{{Host: host}}
+		}
+		return nil
+	}
+	var rules []ExposeRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	*e = rules
+	return nil
+}
+
+// BackingService is a service such as redis which is managed by klstr
+// and deleted along with the parent Muservice.
+type BackingService struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Database is a database created on a registered db instance.
+type Database struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Instance string `json:"instance"`
+}
+
+type MuserviceStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	Replicas           int32 `json:"replicas,omitempty"`
+	AvailableReplicas  int32 `json:"availableReplicas,omitempty"`
+	// IngressAddresses are the load balancer addresses of the ingress
+	// created for the expose rules.
+	IngressAddresses []string `json:"ingressAddresses,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type MuserviceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Muservice `json:"items"`
+}
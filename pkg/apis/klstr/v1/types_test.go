@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExposeUnmarshalHost(t *testing.T) {
+	var spec MuserviceSpec
+	err := json.Unmarshal([]byte(`{"image": "muservice", "expose": "muservice.minikube.local"}`), &spec)
+	if err != nil {
+		t.Error("error unmarshalling expose host ", err)
+	}
+	if len(spec.Expose) != 1 || spec.Expose[0].Host != "muservice.minikube.local" {
+		t.Errorf("expose host not unmarshalled correctly: %+v", spec.Expose)
+	}
+}
+
+func TestExposeUnmarshalRules(t *testing.T) {
+	var spec MuserviceSpec
+	err := json.Unmarshal([]byte(`{
+		"image": "muservice",
+		"expose": [
+			{"host": "a.minikube.local", "paths": ["/api", "/web"]},
+			{"host": "b.minikube.local", "port": 8080}
+		]
+	}`), &spec)
+	if err != nil {
+		t.Error("error unmarshalling expose rules ", err)
+	}
+	if len(spec.Expose) != 2 {
+		t.Fatalf("expected 2 expose rules, got %d", len(spec.Expose))
+	}
+	if len(spec.Expose[0].Paths) != 2 || spec.Expose[1].Port != 8080 {
+		t.Errorf("expose rules not unmarshalled correctly: %+v", spec.Expose)
+	}
+}
@@ -0,0 +1,54 @@
+// Package assets holds the kubernetes manifests under k8s/, compiled into
+// the klstr binary.
+package assets
+
+//go:generate go run ../../hack/gen-assets.go -src ../../k8s -out manifests.go
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// manifestsDir overrides the bundled manifests when set.
+var manifestsDir string
+
+// SetManifestsDir makes ReadFile read manifests from dir instead of the
+// copies bundled into the binary. dir mirrors the layout of k8s/.
+func SetManifestsDir(dir string) {
+	manifestsDir = dir
+}
+
+// ReadFile returns a manifest by its path relative to k8s/, such as
+// "monitoring/grafana-service.yaml".
+func ReadFile(name string) ([]byte, error) {
+	if manifestsDir != "" {
+		return ioutil.ReadFile(filepath.Join(manifestsDir, filepath.FromSlash(name)))
+	}
+	data, ok := manifests[name]
+	if !ok {
+		return nil, fmt.Errorf("manifest %s is not bundled", name)
+	}
+	return []byte(data), nil
+}
+
+// IsManifest tells the files under k8s/ that are bundled: manifests and
+// grafana dashboards.
+func IsManifest(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// Names returns the paths of the bundled manifests.
+func Names() []string {
+	var names []string
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,68 @@
+package assets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestsUpToDate(t *testing.T) {
+	src := filepath.Join("..", "..", "k8s")
+	count := 0
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !IsManifest(path) {
+			return err
+		}
+		count++
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		want, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		got, err := ReadFile(filepath.ToSlash(rel))
+		if err != nil {
+			t.Errorf("%s is not bundled, run make generate", rel)
+			return nil
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run make generate", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(Names()) {
+		t.Errorf("expected %d bundled manifests, got %d", count, len(Names()))
+	}
+}
+
+func TestReadFileFromManifestsDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "klstr-manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.MkdirAll(filepath.Join(dir, "jobs"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "jobs", "dbjob.yaml"), []byte("custom"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetManifestsDir(dir)
+	defer SetManifestsDir("")
+	data, err := ReadFile("jobs/dbjob.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "custom" {
+		t.Errorf("expected the customised manifest, got %q", data)
+	}
+}
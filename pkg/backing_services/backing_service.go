@@ -0,0 +1,69 @@
+package backing_services
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const BackingServiceLabel = "io.klstr/backing-service"
+
+type BackingServiceOptions struct {
+	// Name is the name declared under the Muservice services.
+	Name string
+	// ObjectName is the name used for the kubernetes objects that make up
+	// the backing service.
+	ObjectName      string
+	Namespace       string
+	Labels          map[string]string
+	OwnerReferences []metav1.OwnerReference
+}
+
+type BackingService interface {
+	// BuildObjects returns the kubernetes objects to be created for the
+	// backing service, in creation order.
+	BuildObjects() []runtime.Object
+	// EnvVars returns the environment variables that the parent
+	// Muservice uses to connect to the backing service.
+	EnvVars() []corev1.EnvVar
+}
+
+type BackingServiceFactory func(options BackingServiceOptions) BackingService
+
+var backingServiceFactories = make(map[string]BackingServiceFactory)
+
+func RegisterBackingServiceFactory(
+	name string,
+	backingServiceFactory BackingServiceFactory,
+) {
+	if backingServiceFactory == nil {
+		log.Errorf("Backing Service Factory %s does not exist", name)
+		return
+	}
+	_, registered := backingServiceFactories[name]
+	if registered {
+		log.Errorf("Backing Service Factory %s already registered. Ignoring.", name)
+		return
+	}
+	backingServiceFactories[name] = backingServiceFactory
+}
+
+func init() {
+	RegisterBackingServiceFactory("redis", NewRedisBackingService)
+}
+
+func CreateBackingService(serviceType string, options BackingServiceOptions) (BackingService, error) {
+	backingService, ok := backingServiceFactories[serviceType]
+	if !ok {
+		var availableBackingServices []string
+		for bs := range backingServiceFactories {
+			availableBackingServices = append(availableBackingServices, bs)
+		}
+		return nil, fmt.Errorf("Invalid Backing Service Type: %s. Must be one of: %s", serviceType, strings.Join(availableBackingServices, ", "))
+	}
+	return backingService(options), nil
+}
@@ -0,0 +1,47 @@
+package backing_services
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCreateRedisBackingService(t *testing.T) {
+	bs, err := CreateBackingService("redis", BackingServiceOptions{
+		Name:       "samplecache",
+		ObjectName: "muservice-samplecache",
+		Namespace:  "default",
+	})
+	if err != nil {
+		t.Fatal("error creating redis backing service ", err)
+	}
+	objects := bs.BuildObjects()
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objects))
+	}
+	if _, ok := objects[0].(*corev1.PersistentVolumeClaim); !ok {
+		t.Error("first object is not a persistent volume claim")
+	}
+	service, ok := objects[1].(*corev1.Service)
+	if !ok || service.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Error("second object is not a headless service")
+	}
+	if _, ok := objects[2].(*appsv1.StatefulSet); !ok {
+		t.Error("third object is not a statefulset")
+	}
+	env := bs.EnvVars()
+	if env[0].Name != "SAMPLECACHE_REDIS_HOST" || env[0].Value != "muservice-samplecache" {
+		t.Errorf("unexpected host env var %+v", env[0])
+	}
+	if env[1].Name != "SAMPLECACHE_REDIS_PORT" || env[1].Value != "6379" {
+		t.Errorf("unexpected port env var %+v", env[1])
+	}
+}
+
+func TestCreateUnknownBackingService(t *testing.T) {
+	_, err := CreateBackingService("memcached", BackingServiceOptions{})
+	if err == nil {
+		t.Error("expected error for unknown backing service type")
+	}
+}
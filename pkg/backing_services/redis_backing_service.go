@@ -0,0 +1,150 @@
+package backing_services
+
+import (
+	"strconv"
+
+	"github.com/klstr/klstr/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	RedisImage       = "redis:4.0-alpine"
+	RedisPort        = 6379
+	RedisStorageSize = "1Gi"
+)
+
+type RedisBackingService struct {
+	options BackingServiceOptions
+}
+
+var _ BackingService = RedisBackingService{}
+
+func (rbs RedisBackingService) objectMeta() metav1.ObjectMeta {
+	labels := map[string]string{
+		"app":               rbs.options.ObjectName,
+		BackingServiceLabel: rbs.options.Name,
+	}
+	for k, v := range rbs.options.Labels {
+		labels[k] = v
+	}
+	return metav1.ObjectMeta{
+		Name:            rbs.options.ObjectName,
+		Namespace:       rbs.options.Namespace,
+		Labels:          labels,
+		OwnerReferences: rbs.options.OwnerReferences,
+	}
+}
+
+func (rbs RedisBackingService) podLabels() map[string]string {
+	return map[string]string{
+		"app":               rbs.options.ObjectName,
+		BackingServiceLabel: rbs.options.Name,
+	}
+}
+
+func (rbs RedisBackingService) buildPersistentVolumeClaim() *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: rbs.objectMeta(),
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(RedisStorageSize),
+				},
+			},
+		},
+	}
+}
+
+func (rbs RedisBackingService) buildService() *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: rbs.objectMeta(),
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  rbs.podLabels(),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "redis",
+					Port:       RedisPort,
+					TargetPort: intstr.FromInt(RedisPort),
+				},
+			},
+		},
+	}
+}
+
+func (rbs RedisBackingService) buildStatefulSet() *appsv1.StatefulSet {
+	replicas := int32(1)
+	return &appsv1.StatefulSet{
+		ObjectMeta: rbs.objectMeta(),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: rbs.options.ObjectName,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: rbs.podLabels(),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: rbs.podLabels(),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    "redis",
+							Image:   RedisImage,
+							Command: []string{"redis-server", "--appendonly", "yes"},
+							Ports: []corev1.ContainerPort{
+								{Name: "redis", ContainerPort: RedisPort},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "data", MountPath: "/data"},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "data",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: rbs.options.ObjectName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (rbs RedisBackingService) BuildObjects() []runtime.Object {
+	return []runtime.Object{
+		rbs.buildPersistentVolumeClaim(),
+		rbs.buildService(),
+		rbs.buildStatefulSet(),
+	}
+}
+
+func (rbs RedisBackingService) EnvVars() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  util.EnvVarName(rbs.options.Name, "REDIS_HOST"),
+			Value: rbs.options.ObjectName,
+		},
+		{
+			Name:  util.EnvVarName(rbs.options.Name, "REDIS_PORT"),
+			Value: strconv.Itoa(RedisPort),
+		},
+	}
+}
+
+func NewRedisBackingService(options BackingServiceOptions) BackingService {
+	return &RedisBackingService{
+		options: options,
+	}
+}
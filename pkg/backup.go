@@ -0,0 +1,331 @@
+package klstr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/klstr/klstr/pkg/command_jobs"
+	"github.com/klstr/klstr/pkg/util"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// backupTimeLayout is the layout of the time in the backup names.
+const backupTimeLayout = "20060102T150405Z"
+
+// BackupStore is the s3 compatible bucket database backups are streamed
+// to, such as a minio running in the cluster.
+type BackupStore struct {
+	Endpoint  string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Prefix is prepended to the keys of the backups.
+	Prefix string
+	// Namespace is the klstr namespace the store is recorded in.
+	Namespace string
+}
+
+// BackupInfo is a backup in the backup store.
+type BackupInfo struct {
+	// ID is the key of the backup below the store prefix.
+	ID       string
+	DBType   string
+	Instance string
+	Database string
+	Time     time.Time
+	Size     int64
+}
+
+// ConfigureBackupStore records the backup store, replacing the previous
+// one.
+func ConfigureBackupStore(bs *BackupStore, kubeconfig string) error {
+	endpoint, err := url.Parse(bs.Endpoint)
+	if err != nil || endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Host == "" {
+		return fmt.Errorf("invalid backup store endpoint %q, must be an http or https url", bs.Endpoint)
+	}
+	if bs.Bucket == "" {
+		return errors.New("no backup store bucket given")
+	}
+	if bs.AccessKey != "" && bs.SecretKey == "" {
+		return errors.New("no backup store secret key given")
+	}
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	err = util.EnsureNamespace(cs, bs.Namespace)
+	if err != nil {
+		return err
+	}
+	prefix := strings.Trim(bs.Prefix, "/")
+	if prefix == "" {
+		prefix = command_jobs.DefaultBackupPrefix
+	}
+	si := cs.CoreV1().Secrets(bs.Namespace)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: command_jobs.BackupStoreSecret,
+		},
+		StringData: map[string]string{
+			"endpoint":   bs.Endpoint,
+			"bucket":     bs.Bucket,
+			"access-key": bs.AccessKey,
+			"secret-key": bs.SecretKey,
+			"prefix":     prefix,
+		},
+	}
+	current, err := si.Get(secret.Name, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		_, err = si.Create(secret)
+	case err == nil:
+		current.Data = nil
+		current.StringData = secret.StringData
+		_, err = si.Update(current)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("backups are stored in %s/%s/%s\n", strings.TrimSuffix(bs.Endpoint, "/"), bs.Bucket, prefix)
+	return nil
+}
+
+func BackupDB(dc *DatabaseConfig, kubeconfig string) error {
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	dj := DatabaseJob{
+		cs: cs,
+		dc: dc,
+	}
+	return dj.BackupDBJob()
+}
+
+// ScheduleBackup backs the database up on schedule, a cron expression,
+// with a cron job replacing the previous schedule of the database.
+func ScheduleBackup(dc *DatabaseConfig, schedule string, kubeconfig string) error {
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	dj := DatabaseJob{
+		cs: cs,
+		dc: dc,
+	}
+	return dj.ScheduleBackupCronJob(schedule)
+}
+
+func UnscheduleBackup(dc *DatabaseConfig, kubeconfig string) error {
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	name := backupCronJobName(dc)
+	err = cs.BatchV1beta1().CronJobs(dc.Namespace).Delete(name, backgroundDeletion())
+	if kerrors.IsNotFound(err) {
+		return fmt.Errorf("no backups of database %s are scheduled", dc.DBName)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("backups of database %s unscheduled\n", dc.DBName)
+	return nil
+}
+
+// ListBackups lists the backups in the store, narrowed down to the type,
+// instance and database of dc when they are set.
+func ListBackups(dc *DatabaseConfig, kubeconfig string) ([]BackupInfo, error) {
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	dj := DatabaseJob{
+		cs: cs,
+		dc: dc,
+	}
+	return dj.ListBackupsJob()
+}
+
+func (dj *DatabaseJob) BackupDBJob() error {
+	jobobj, err := dj.backupJob()
+	if err != nil {
+		return err
+	}
+	_, err = dj.runJob(jobobj, "backup", os.Stdout)
+	return err
+}
+
+func (dj *DatabaseJob) ScheduleBackupCronJob(schedule string) error {
+	if schedule == "" {
+		return errors.New("no schedule given")
+	}
+	job, err := dj.backupJob()
+	if err != nil {
+		return err
+	}
+	if dj.dc.Timeout > 0 {
+		activeDeadlineSeconds := int64(dj.dc.Timeout / time.Second)
+		job.Spec.ActiveDeadlineSeconds = &activeDeadlineSeconds
+	}
+	job.Labels[DatabaseCommandLabel] = "backup"
+	historyLimit := int32(3)
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: backupCronJobName(dj.dc),
+			Labels: map[string]string{
+				util.DatabaseLabel:   dj.dc.DBName,
+				util.DBInstanceLabel: dj.dc.DBIName,
+			},
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: job.Labels},
+				Spec:       job.Spec,
+			},
+		},
+	}
+	ci := dj.cs.BatchV1beta1().CronJobs(dj.dc.Namespace)
+	current, err := ci.Get(cronJob.Name, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		_, err = ci.Create(cronJob)
+	case err == nil:
+		current.Labels = cronJob.Labels
+		current.Spec = cronJob.Spec
+		_, err = ci.Update(current)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("backups of database %s scheduled at %q by cron job %s/%s\n", dj.dc.DBName, schedule, dj.dc.Namespace, cronJob.Name)
+	return nil
+}
+
+func (dj *DatabaseJob) ListBackupsJob() ([]BackupInfo, error) {
+	err := dj.checkBackupStore()
+	if err != nil {
+		return nil, err
+	}
+	if dj.dc.DBName != "" && dj.dc.DBIName == "" || dj.dc.DBIName != "" && dj.dc.DBType == "" {
+		return nil, errors.New("listing the backups of a database needs its instance and type")
+	}
+	prefix := ""
+	for _, part := range []string{dj.dc.DBType, dj.dc.DBIName, dj.dc.DBName} {
+		if part != "" {
+			prefix += part + "/"
+		}
+	}
+	jobobj, err := command_jobs.NewBackupListJob(prefix)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := dj.runJob(jobobj, "backups", ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	return parseBackupList(prefix, logs), nil
+}
+
+// backupJob returns the job streaming a dump of the database to the
+// backup store.
+func (dj *DatabaseJob) backupJob() (*batchv1.Job, error) {
+	if dj.dc.DBName == "" {
+		return nil, errors.New("no database given")
+	}
+	err := dj.checkBackupStore()
+	if err != nil {
+		return nil, err
+	}
+	jobobj, err := command_jobs.NewJobFromTemplate()
+	if err != nil {
+		return nil, err
+	}
+	cj, err := command_jobs.CreateCommandJob(dj.dc.DBType, command_jobs.CommandJobOptions{
+		DBName:        dj.dc.DBName,
+		DBIName:       dj.dc.DBIName,
+		RetentionDays: dj.dc.RetentionDays,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cj.BuildBackupCommand(jobobj)
+	if jobobj.Labels == nil {
+		jobobj.Labels = map[string]string{}
+	}
+	jobobj.Labels[util.DatabaseLabel] = dj.dc.DBName
+	jobobj.Labels[util.DBInstanceLabel] = dj.dc.DBIName
+	return jobobj, nil
+}
+
+func (dj *DatabaseJob) checkBackupStore() error {
+	_, err := dj.cs.CoreV1().Secrets(dj.dc.Namespace).Get(command_jobs.BackupStoreSecret, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return errors.New("no backup store configured, run klstr database backups configure first")
+	}
+	return err
+}
+
+// backupCronJobName is derived from the database, within the 52 character
+// limit on cron job names.
+func backupCronJobName(dc *DatabaseConfig) string {
+	name := strings.ToLower(fmt.Sprintf("dbbackup-%s-%s-%s", dc.DBType, dc.DBIName, dc.DBName))
+	name = strings.Replace(name, "_", "-", -1)
+	if len(name) > 52 {
+		name = strings.TrimRight(name[:52], "-")
+	}
+	return name
+}
+
+// parseBackupList parses the json lines the minio client lists the
+// backups below prefix with. Objects not named like backups are skipped.
+func parseBackupList(prefix, logs string) []BackupInfo {
+	var backups []BackupInfo
+	for _, line := range strings.Split(logs, "\n") {
+		var object struct {
+			Status string `json:"status"`
+			Type   string `json:"type"`
+			Key    string `json:"key"`
+			Size   int64  `json:"size"`
+		}
+		if json.Unmarshal([]byte(line), &object) != nil || object.Type != "file" {
+			continue
+		}
+		id := prefix + object.Key
+		parts := strings.Split(id, "/")
+		if len(parts) != 4 {
+			continue
+		}
+		name := path.Base(id)
+		taken, err := time.Parse(backupTimeLayout, name[:strings.Index(name+".", ".")])
+		if err != nil {
+			log.Debugf("skipping object %s not named like a backup", id)
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			ID:       id,
+			DBType:   parts[0],
+			Instance: parts[1],
+			Database: parts[2],
+			Time:     taken,
+			Size:     object.Size,
+		})
+	}
+	return backups
+}
@@ -0,0 +1,81 @@
+package klstr
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseBackupList(t *testing.T) {
+	taken := time.Date(2018, 10, 18, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		prefix   string
+		logs     string
+		expected []BackupInfo
+	}{
+		{"empty output", "", "", nil},
+		{
+			"all backups",
+			"",
+			`{"status":"success","type":"file","key":"pg/dev/orders/20181018T030000Z.dump","size":1258291}` + "\n" +
+				`{"status":"success","type":"file","key":"mysql/dev/users/20181018T030000Z.sql.gz","size":2048}` + "\n",
+			[]BackupInfo{
+				{ID: "pg/dev/orders/20181018T030000Z.dump", DBType: "pg", Instance: "dev", Database: "orders", Time: taken, Size: 1258291},
+				{ID: "mysql/dev/users/20181018T030000Z.sql.gz", DBType: "mysql", Instance: "dev", Database: "users", Time: taken, Size: 2048},
+			},
+		},
+		{
+			"backups below a prefix",
+			"pg/dev/",
+			`{"status":"success","type":"file","key":"orders/20181018T030000Z.dump","size":10}` + "\n",
+			[]BackupInfo{
+				{ID: "pg/dev/orders/20181018T030000Z.dump", DBType: "pg", Instance: "dev", Database: "orders", Time: taken, Size: 10},
+			},
+		},
+		{
+			"objects not named like backups",
+			"",
+			"mc: <WARNING> listing\n" +
+				`{"status":"success","type":"folder","key":"pg/dev/orders/"}` + "\n" +
+				`{"status":"success","type":"file","key":"uploads/restore-1539831600","size":10}` + "\n" +
+				`{"status":"success","type":"file","key":"pg/dev/orders/latest.dump","size":10}` + "\n" +
+				`{"status":"success","type":"file","key":"pg/dev/orders/old/20181018T030000Z.dump","size":10}` + "\n",
+			nil,
+		},
+	}
+	for _, test := range tests {
+		got := parseBackupList(test.prefix, test.logs)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestBackupCronJobName(t *testing.T) {
+	tests := []struct {
+		dc       DatabaseConfig
+		expected string
+	}{
+		{DatabaseConfig{DBType: "pg", DBIName: "dev", DBName: "orders"}, "dbbackup-pg-dev-orders"},
+		{DatabaseConfig{DBType: "mysql", DBIName: "Dev", DBName: "order_items"}, "dbbackup-mysql-dev-order-items"},
+		{
+			DatabaseConfig{DBType: "pg", DBIName: "production", DBName: "customer_order_history_archive"},
+			"dbbackup-pg-production-customer-order-history-archiv",
+		},
+		{
+			// the name is not left ending in a dash when truncated
+			DatabaseConfig{DBType: "pg", DBIName: "production", DBName: "customer_order_history_archiv_x"},
+			"dbbackup-pg-production-customer-order-history-archiv",
+		},
+	}
+	for _, test := range tests {
+		got := backupCronJobName(&test.dc)
+		if got != test.expected {
+			t.Errorf("expected cron job name %s, got %s", test.expected, got)
+		}
+		if len(got) > 52 {
+			t.Errorf("expected cron job name %s to fit 52 characters, got %d", got, len(got))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package command_jobs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// BackupStoreSecret holds the s3 compatible bucket backups are
+	// stored in: its endpoint, bucket, credentials and key prefix.
+	BackupStoreSecret = "klstr-backup-store"
+	// DefaultBackupPrefix is the key prefix of the backups when the
+	// store does not set one.
+	DefaultBackupPrefix = "klstr"
+
+	// mcImage provides the minio client the backup jobs copy into the
+	// database image to stream dumps to the store.
+	mcImage        = "minio/mc:RELEASE.2020-10-03T02-54-56Z"
+	backupToolsDir = "/klstr-tools"
+)
+
+// backupStoreEnv exposes the backup store to the job scripts.
+func backupStoreEnv() []corev1.EnvVar {
+	storeEnv := func(name, key string, optional bool) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					Key:      key,
+					Optional: &optional,
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: BackupStoreSecret,
+					},
+				},
+			},
+		}
+	}
+	return []corev1.EnvVar{
+		storeEnv("S3_ENDPOINT", "endpoint", false),
+		storeEnv("S3_BUCKET", "bucket", false),
+		storeEnv("S3_ACCESS_KEY", "access-key", false),
+		storeEnv("S3_SECRET_KEY", "secret-key", false),
+		storeEnv("S3_PREFIX", "prefix", true),
+	}
+}
+
+// mcSetup defines mc as the minio client pointed at the backup store, and
+// the store path of the backups.
+func mcSetup() []string {
+	return []string{
+		fmt.Sprintf("mc() { %s/mc --config-dir=%s/.mc --quiet \"$@\"; };", backupToolsDir, backupToolsDir),
+		"mc alias set store \"$S3_ENDPOINT\" \"$S3_ACCESS_KEY\" \"$S3_SECRET_KEY\" > /dev/null &&",
+		fmt.Sprintf("backups=\"store/$S3_BUCKET/${S3_PREFIX:-%s}\" &&", DefaultBackupPrefix),
+	}
+}
+
+// withBackupTools copies the minio client into a volume shared with the
+// container of object, and exposes the backup store to it.
+func withBackupTools(object *batchv1.Job) {
+	spec := &object.Spec.Template.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name:         "klstr-tools",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	mount := corev1.VolumeMount{Name: "klstr-tools", MountPath: backupToolsDir}
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:         "mc",
+		Image:        mcImage,
+		Command:      []string{"cp", "/usr/bin/mc", backupToolsDir + "/mc"},
+		VolumeMounts: []corev1.VolumeMount{mount},
+	})
+	spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, mount)
+	spec.Containers[0].Env = append(spec.Containers[0].Env, backupStoreEnv()...)
+}
+
+// BackupKey returns the key of the backups of a database below the store
+// prefix. Backups are named by the time they were taken.
+func BackupKey(dbType, dbiName, dbName string) string {
+	return strings.Join([]string{dbType, dbiName, dbName}, "/")
+}
+
+// backupScript streams the output of dump to the store, named by the
+// current time and tagged with the database it was taken from. A failed
+// dump removes the partial upload. Backups older than the retention of
+// options are pruned afterwards.
+func backupScript(dbType string, options CommandJobOptions, dump string, extension string) []string {
+	key := BackupKey(dbType, options.DBIName, options.DBName)
+	script := append(mcSetup(),
+		fmt.Sprintf("backup=%s\"$(date -u +%%Y%%m%%dT%%H%%M%%SZ).%s\" &&", shellQuote(key+"/"), extension),
+		"if !", dump, "|",
+		fmt.Sprintf(
+			"mc pipe --attr %s \"$backups/$backup\";",
+			shellQuote(fmt.Sprintf("klstr-type=%s;klstr-instance=%s;klstr-database=%s", dbType, options.DBIName, options.DBName)),
+		),
+		"then mc rm --force \"$backups/$backup\" > /dev/null 2>&1; exit 1; fi &&",
+		"echo \"backup $backup uploaded\"",
+	)
+	if options.RetentionDays > 0 {
+		script = append(script,
+			"&&",
+			fmt.Sprintf("mc rm --recursive --force --older-than %dd \"$backups\"/%s", options.RetentionDays, shellQuote(key+"/")),
+		)
+	}
+	return script
+}
+
+// NewBackupListJob returns a job listing the backups below prefix as json
+// lines of the minio client.
+func NewBackupListJob(prefix string) (*batchv1.Job, error) {
+	object, err := NewJobFromTemplate()
+	if err != nil {
+		return nil, err
+	}
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-backups-%d", sid)
+	container := &object.Spec.Template.Spec.Containers[0]
+	container.Name = "backups"
+	container.Image = mcImage
+	container.Env = nil
+	withBackupTools(object)
+	script := append(mcSetup(), fmt.Sprintf("mc ls --json --recursive \"$backups\"/%s", shellQuote(prefix)))
+	container.Command = []string{"/bin/sh", "-c", strings.Join(script, " ")}
+	return object, nil
+}
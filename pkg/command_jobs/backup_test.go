@@ -0,0 +1,57 @@
+package command_jobs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildBackupCommand(t *testing.T) {
+	hostile := "o'rders; rm -rf / #"
+	tests := []struct {
+		dbType   string
+		dbName   string
+		contains []string
+	}{
+		{"pg", "orders", []string{"pg_dump --host", "--format=custom 'orders'", "backup='pg/dev/orders/'\"$(date"}},
+		{"mysql", "orders", []string{"--triggers 'orders' | gzip", "backup='mysql/dev/orders/'\"$(date"}},
+		{"pg", hostile, []string{"--format=custom " + shellQuote(hostile), "backup=" + shellQuote("pg/dev/"+hostile+"/")}},
+		{"mysql", hostile, []string{"--triggers " + shellQuote(hostile), "backup=" + shellQuote("mysql/dev/"+hostile+"/")}},
+	}
+	for _, test := range tests {
+		job, err := NewJobFromTemplate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cj, err := CreateCommandJob(test.dbType, CommandJobOptions{DBName: test.dbName, DBIName: "dev", RetentionDays: 14})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cj.BuildBackupCommand(job)
+		command := job.Spec.Template.Spec.Containers[0].Command
+		script := command[len(command)-1]
+		contains := append(test.contains,
+			"mc pipe --attr "+shellQuote("klstr-type="+test.dbType+";klstr-instance=dev;klstr-database="+test.dbName),
+			"--older-than 14d \"$backups\"/"+shellQuote(test.dbType+"/dev/"+test.dbName+"/"),
+		)
+		for _, s := range contains {
+			if !strings.Contains(script, s) {
+				t.Errorf("%s %s: expected the script to contain %q, got %s", test.dbType, test.dbName, s, script)
+			}
+		}
+		if test.dbName == hostile && strings.Contains(script, hostile) {
+			t.Errorf("%s: expected the database name to be quoted, got %s", test.dbType, script)
+		}
+	}
+}
+
+func TestNewBackupListJobQuotesPrefix(t *testing.T) {
+	job, err := NewBackupListJob("pg/dev/o'rders/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := job.Spec.Template.Spec.Containers[0].Command
+	script := command[len(command)-1]
+	if !strings.HasSuffix(script, "mc ls --json --recursive \"$backups\"/'pg/dev/o'\\''rders/'") {
+		t.Errorf("expected the prefix to be quoted, got %s", script)
+	}
+}
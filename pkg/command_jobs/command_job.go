@@ -4,19 +4,51 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
 	log "github.com/sirupsen/logrus"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 )
 
 type CommandJobOptions struct {
 	DBName   string
 	ToDBName string
 	DBIName  string
+	Username string
+	// PasswordSecret names the secret in the namespace of the job holding
+	// the password of Username under the password key, so that the
+	// password is not written into the job.
+	PasswordSecret string
+	// Owner marks the user as created by klstr for it. The create with
+	// user command refuses to take over a user or database that exists
+	// without the same mark. Existing users are taken over when it is
+	// empty.
+	Owner string
+	// RetentionDays prunes the backups of the database older than it
+	// after a backup, when set.
+	RetentionDays int
+	// Restore is the dump restored into the database.
+	Restore *RestoreSource
 }
 
 type CommandJob interface {
 	BuildCreateCommand(object *batchv1.Job)
 	BuildCloneCommand(object *batchv1.Job)
+	// BuildCreateWithUserCommand creates a database along with a user
+	// that owns it. The database and the user are only created when they
+	// do not exist, so that the job can be run again.
+	BuildCreateWithUserCommand(object *batchv1.Job)
+	// BuildListCommand lists the databases of the instance, printing a
+	// tab separated line of name, owners and size in bytes for each.
+	BuildListCommand(object *batchv1.Job)
+	BuildDropCommand(object *batchv1.Job)
+	// BuildBackupCommand streams a dump of the database to the backup
+	// store.
+	BuildBackupCommand(object *batchv1.Job)
+	// BuildRestoreCommand restores the dump of the Restore option into
+	// the database, and verifies the tables of the dump were restored.
+	BuildRestoreCommand(object *batchv1.Job)
 }
 
 type CommandJobFactory func(options CommandJobOptions) CommandJob
@@ -55,3 +87,41 @@ func CreateCommandJob(dbType string, options CommandJobOptions) (CommandJob, err
 	}
 	return commandJob(options), nil
 }
+
+func NewJobFromTemplate() (*batchv1.Job, error) {
+	data, err := assets.ReadFile("jobs/dbjob.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return object.(*batchv1.Job), nil
+}
+
+// userPasswordVar holds the password of the user created along with a
+// database in the job scripts.
+const userPasswordVar = "DBUSERPASSWORD"
+
+// userPasswordEnv reads the password of the user created along with a
+// database from secret.
+func userPasswordEnv(secret string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: userPasswordVar,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				Key: "password",
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secret,
+				},
+			},
+		},
+	}
+}
+
+// shellQuote quotes s as a single word of a shell script.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,73 @@
+package command_jobs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildDropCommand(t *testing.T) {
+	tests := []struct {
+		dbType   string
+		dbName   string
+		expected string
+	}{
+		{"pg", "orders", `--command=drop database "orders"`},
+		{"pg", `my"db; drop database users`, `--command=drop database "my""db; drop database users"`},
+		{"mysql", "orders", "--execute='drop database `orders`'"},
+		{"mysql", "it's`db", "--execute='drop database `it'\\''s``db`'"},
+	}
+	for _, test := range tests {
+		job, err := NewJobFromTemplate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cj, err := CreateCommandJob(test.dbType, CommandJobOptions{DBName: test.dbName, DBIName: "dev"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cj.BuildDropCommand(job)
+		command := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+		if !strings.Contains(command, test.expected) {
+			t.Errorf("%s: expected the command to contain %s, got %s", test.dbType, test.expected, command)
+		}
+	}
+}
+
+func TestBuildCreateWithUserCommandChecksOwner(t *testing.T) {
+	tests := []struct {
+		dbType string
+		owner  string
+		checks []string
+	}{
+		{"pg", "default/muservice/dev/orders", []string{"--set=owner='default/muservice/dev/orders'", "shobj_description", "comment on role"}},
+		{"mysql", "default/muservice/dev/orders", []string{"[ \"$existing\" != 'owner:default/muservice/dev/orders' ]", "insert into klstr.users"}},
+		{"pg", "", nil},
+		{"mysql", "", nil},
+	}
+	for _, test := range tests {
+		job, err := NewJobFromTemplate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cj, err := CreateCommandJob(test.dbType, CommandJobOptions{
+			DBName:         "orders",
+			DBIName:        "dev",
+			Username:       "muservice_orders_0a1b2c3d4e",
+			PasswordSecret: "dbjob-provision-0a1b2c3d4e",
+			Owner:          test.owner,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cj.BuildCreateWithUserCommand(job)
+		command := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+		for _, check := range test.checks {
+			if !strings.Contains(command, check) {
+				t.Errorf("%s: expected the command to contain %s, got %s", test.dbType, check, command)
+			}
+		}
+		if test.owner == "" && (strings.Contains(command, "shobj_description") || strings.Contains(command, "klstr.users")) {
+			t.Errorf("%s: expected no owner check without an owner, got %s", test.dbType, command)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package command_jobs
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -64,30 +65,43 @@ func (mcj MySQLCommandJob) getJobEnv() []corev1.EnvVar {
 	}
 }
 
-func (mcj MySQLCommandJob) getJobCommand(command []string) []string {
-	return append([]string{
+// mysqlClient returns the invocation of a mysql client program with the
+// credentials of the db instance. The credentials are expanded by the shell
+// so that special characters in them survive.
+func (mcj MySQLCommandJob) mysqlClient(program string) string {
+	return fmt.Sprintf(
+		"%s --host=\"$MYSQLHOST\" --port=\"$MYSQLPORT\" --user=\"$MYSQLUSERNAME\" --password=\"$MYSQLPASSWORD\"",
+		program,
+	)
+}
+
+// getJobCommand runs script with pipefail so that a failing mysqldump
+// fails the job.
+func (mcj MySQLCommandJob) getJobCommand(script ...string) []string {
+	return []string{
 		"/bin/bash",
+		"-o",
+		"pipefail",
 		"-c",
-		"mysql",
-		"--host=$(MYSQLHOST)",
-		"--port=$(MYSQLPORT)",
-		"--user=$(MYSQLUSERNAME)",
-		"--password=$(MYSQLPASSWORD)",
-	}, command...)
+		strings.Join(script, " "),
+	}
 }
 
 func (mcj MySQLCommandJob) BuildCloneCommand(object *batchv1.Job) {
 	sid := time.Now().Unix()
 	object.ObjectMeta.Name = fmt.Sprintf("dbjob-clone-%d", sid)
 	object.Spec.Template.Spec.Containers[0].Image = "mysql"
-	cmd := []string{
-		fmt.Sprintf("--execute=create database %s", mcj.options.ToDBName),
+	script := []string{
+		mcj.mysqlClient("mysql"),
+		fmt.Sprintf("--execute='create database %s'", mcj.options.ToDBName),
 		"&&",
-		fmt.Sprintf("mysqldump %s", mcj.options.DBName),
+		mcj.mysqlClient("mysqldump"),
+		mcj.options.DBName,
 		"|",
-		fmt.Sprintf("mysql %s", mcj.options.ToDBName),
+		mcj.mysqlClient("mysql"),
+		mcj.options.ToDBName,
 	}
-	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(cmd)
+	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(script...)
 	object.Spec.Template.Spec.Containers[0].Env = mcj.getJobEnv()
 }
 
@@ -95,11 +109,180 @@ func (mcj MySQLCommandJob) BuildCreateCommand(object *batchv1.Job) {
 	sid := time.Now().Unix()
 	object.ObjectMeta.Name = fmt.Sprintf("dbjob-create-%d", sid)
 	object.Spec.Template.Spec.Containers[0].Image = "mysql"
-	cmd := []string{
-		fmt.Sprintf("--execute=create database %s", mcj.options.ToDBName),
+	script := []string{
+		mcj.mysqlClient("mysql"),
+		fmt.Sprintf("--execute='create database %s'", mcj.options.DBName),
+	}
+	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(script...)
+	object.Spec.Template.Spec.Containers[0].Env = mcj.getJobEnv()
+}
+
+// BuildCreateWithUserCommand resets the password of an existing user
+// instead of creating it. The password is escaped by the shell and written
+// to the statements on stdin. With an owner, the user is recorded with it
+// in the klstr database, and users and databases of others fail the job.
+func (mcj MySQLCommandJob) BuildCreateWithUserCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-create-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "mysql"
+	dbName := mysqlIdentifier(mcj.options.DBName)
+	user := fmt.Sprintf("%s@'%%'", mysqlString(mcj.options.Username))
+	withPassword := func(statement string) string {
+		return shellQuote(statement+" identified by '") + "\"$pw\"" + shellQuote("';")
+	}
+	var script []string
+	statements := []string{
+		shellQuote(fmt.Sprintf("create database if not exists %s;", dbName)),
+		withPassword(fmt.Sprintf("create user if not exists %s", user)),
+		withPassword(fmt.Sprintf("alter user %s", user)),
+		shellQuote(fmt.Sprintf("grant all privileges on %s.* to %s;", dbName, user)),
+	}
+	if mcj.options.Owner != "" {
+		script = mcj.checkOwner()
+		statements = append(statements, shellQuote(fmt.Sprintf(
+			"insert into %s (user, owner) values (%s, %s) on duplicate key update owner = values(owner);",
+			mysqlUsersTable,
+			mysqlString(mcj.options.Username),
+			mysqlString(mcj.options.Owner),
+		)))
+	}
+	script = append(script, fmt.Sprintf(`pw=${%s//\\/\\\\} && pw=${pw//\'/\'\'} &&`, userPasswordVar), "printf '%s\\n'")
+	script = append(script, statements...)
+	script = append(script, "|", mcj.mysqlClient("mysql"))
+	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(script...)
+	object.Spec.Template.Spec.Containers[0].Env = append(
+		mcj.getJobEnv(),
+		userPasswordEnv(mcj.options.PasswordSecret),
+	)
+}
+
+// mysqlUsersTable records the owner of the users klstr created, as mysql
+// users have no comment to keep it in.
+const mysqlUsersTable = "klstr.users"
+
+// checkOwner returns the start of a script failing when the user exists
+// but was not created for the owner, or when the database exists without
+// the user, as it then belongs to someone else.
+func (mcj MySQLCommandJob) checkOwner() []string {
+	query := func(statement string) string {
+		return fmt.Sprintf("%s --batch --skip-column-names --execute=%s", mcj.mysqlClient("mysql"), shellQuote(statement))
+	}
+	username := mysqlString(mcj.options.Username)
+	owner := "owner:" + mcj.options.Owner
+	return []string{
+		query("create database if not exists klstr; create table if not exists "+mysqlUsersTable+
+			" (user varchar(32) primary key, owner varchar(255) not null)") + " &&",
+		"existing=$(" + query(fmt.Sprintf(
+			"select concat('owner:', coalesce((select owner from %s where user = %s), '')) from mysql.user where user = %s and host = '%%'",
+			mysqlUsersTable, username, username,
+		)) + ") &&",
+		"database=$(" + query(fmt.Sprintf(
+			"select schema_name from information_schema.schemata where schema_name = %s",
+			mysqlString(mcj.options.DBName),
+		)) + ") &&",
+		fmt.Sprintf(
+			"if [ -n \"$existing\" ] && [ \"$existing\" != %s ]; then echo %s >&2; exit 1; fi &&",
+			shellQuote(owner),
+			shellQuote(fmt.Sprintf("ERROR: user %s exists and was not created by klstr for %s", mcj.options.Username, mcj.options.Owner)),
+		),
+		fmt.Sprintf(
+			"if [ -z \"$existing\" ] && [ -n \"$database\" ]; then echo %s >&2; exit 1; fi &&",
+			shellQuote(fmt.Sprintf("ERROR: database %s exists and was not created by klstr for %s", mcj.options.DBName, mcj.options.Owner)),
+		),
+	}
+}
+
+// mysqlIdentifier quotes name as a mysql identifier.
+func mysqlIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// mysqlString quotes s as a mysql string literal.
+func mysqlString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// BuildListCommand reports the users granted privileges on a database as
+// its owners, as mysql databases have no owner.
+func (mcj MySQLCommandJob) BuildListCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-list-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "mysql"
+	script := []string{
+		mcj.mysqlClient("mysql"),
+		"--batch",
+		"--skip-column-names",
+		"--execute=\"select s.schema_name," +
+			" coalesce((select group_concat(distinct p.grantee) from information_schema.schema_privileges p where p.table_schema = s.schema_name), '')," +
+			" coalesce(sum(t.data_length + t.index_length), 0)" +
+			" from information_schema.schemata s left join information_schema.tables t on t.table_schema = s.schema_name" +
+			" where s.schema_name not in ('mysql', 'information_schema', 'performance_schema', 'sys', 'klstr')" +
+			" group by s.schema_name order by s.schema_name\"",
+	}
+	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(script...)
+	object.Spec.Template.Spec.Containers[0].Env = mcj.getJobEnv()
+}
+
+func (mcj MySQLCommandJob) BuildDropCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-drop-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "mysql"
+	script := []string{
+		mcj.mysqlClient("mysql"),
+		"--execute=" + shellQuote("drop database "+mysqlIdentifier(mcj.options.DBName)),
 	}
-	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(cmd)
+	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(script...)
+	object.Spec.Template.Spec.Containers[0].Env = mcj.getJobEnv()
+}
+
+func (mcj MySQLCommandJob) BuildBackupCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-backup-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "mysql"
+	object.Spec.Template.Spec.Containers[0].Env = mcj.getJobEnv()
+	withBackupTools(object)
+	dump := strings.Join([]string{
+		mcj.mysqlClient("mysqldump"),
+		"--single-transaction",
+		"--routines",
+		"--triggers",
+		shellQuote(mcj.options.DBName),
+		"| gzip",
+	}, " ")
+	script := backupScript("mysql", mcj.options, dump, "sql.gz")
+	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(script...)
+}
+
+// BuildRestoreCommand restores the dump into the database, creating it
+// when it does not exist. mysqldump drops the tables it dumps before
+// creating them, so the tables of the dump replace the existing ones.
+func (mcj MySQLCommandJob) BuildRestoreCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-restore-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "mysql"
 	object.Spec.Template.Spec.Containers[0].Env = mcj.getJobEnv()
+	withBackupTools(object)
+	dbName := mcj.options.DBName
+	script, dump := restoreDump(mcj.options.Restore)
+	before, after := verifyRestore(
+		fmt.Sprintf("%s | %s", dump, dumpTableCount),
+		fmt.Sprintf(
+			"%s --batch --skip-column-names --execute=%s",
+			mcj.mysqlClient("mysql"),
+			shellQuote("select count(*) from information_schema.tables where table_schema = "+mysqlString(dbName)),
+		),
+		dbName,
+	)
+	script = append(script, before...)
+	script = append(script,
+		mcj.mysqlClient("mysql"),
+		fmt.Sprintf("--execute=%s &&", shellQuote("create database if not exists "+mysqlIdentifier(dbName))),
+		fmt.Sprintf("echo %s &&", shellQuote("restoring into database "+dbName)),
+		dump, "|", mcj.mysqlClient("mysql"), shellQuote(dbName), "&&",
+	)
+	script = append(script, after...)
+	object.Spec.Template.Spec.Containers[0].Command = mcj.getJobCommand(script...)
 }
 
 func NewMySQLCommandJob(options CommandJobOptions) CommandJob {
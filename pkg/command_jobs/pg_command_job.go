@@ -2,6 +2,7 @@ package command_jobs
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -70,6 +71,7 @@ func (pgcj PGCommandJob) getJobCommand(command []string) []string {
 		"--host=$(PGHOST)",
 		"--port=$(PGPORT)",
 		"--username=$(PGUSERNAME)",
+		"--set=ON_ERROR_STOP=1",
 	}, command...)
 }
 
@@ -102,6 +104,169 @@ func (pgcj PGCommandJob) BuildCreateCommand(object *batchv1.Job) {
 	object.Spec.Template.Spec.Containers[0].Env = pgcj.getJobEnv()
 }
 
+// BuildCreateWithUserCommand resets the password of an existing role
+// instead of creating it. The role is set up in a transaction of its own
+// as create database cannot run in one. With an owner, the role is marked
+// with it in its comment, and roles and databases of others fail the job.
+func (pgcj PGCommandJob) BuildCreateWithUserCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-create-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "postgres"
+	psql := fmt.Sprintf(
+		"%s --set=ON_ERROR_STOP=1 --dbname=postgres --set=db=%s --set=user=%s --set=owner=%s --set=password=\"$%s\"",
+		pgcj.pgClient("psql"),
+		shellQuote(pgcj.options.DBName),
+		shellQuote(pgcj.options.Username),
+		shellQuote(pgcj.options.Owner),
+		userPasswordVar,
+	)
+	var statements []string
+	if pgcj.options.Owner != "" {
+		statements = append(statements,
+			"select "+pgRaise("format('role %s exists and was not created by klstr for %s', rolname, :'owner')")+
+				" from pg_roles where rolname = :'user' and coalesce(shobj_description(oid, 'pg_authid'), '') <> :'owner' \\gexec",
+			"select "+pgRaise("format('database %s is owned by %s', datname, pg_get_userbyid(datdba))")+
+				" from pg_database where datname = :'db' and pg_get_userbyid(datdba) <> :'user' \\gexec",
+		)
+	}
+	statements = append(statements,
+		"begin;",
+		"select format(case when exists (select 1 from pg_roles where rolname = :'user')"+
+			" then 'alter role %I with login password %L'"+
+			" else 'create role %I with login password %L' end, :'user', :'password') \\gexec",
+	)
+	if pgcj.options.Owner != "" {
+		statements = append(statements, "select format('comment on role %I is %L', :'user', :'owner') \\gexec")
+	}
+	statements = append(statements,
+		"commit;",
+		"select format('create database %I owner %I', :'db', :'user')"+
+			" where not exists (select 1 from pg_database where datname = :'db') \\gexec",
+		"begin;",
+		"alter database :\"db\" owner to :\"user\";",
+		"revoke all on database :\"db\" from public;",
+		"commit;",
+	)
+	object.Spec.Template.Spec.Containers[0].Command = []string{
+		"/bin/bash",
+		"-c",
+		fmt.Sprintf("%s <<'SQL'\n%s\nSQL\n", psql, strings.Join(statements, "\n")),
+	}
+	object.Spec.Template.Spec.Containers[0].Env = append(
+		pgcj.getJobEnv(),
+		userPasswordEnv(pgcj.options.PasswordSecret),
+	)
+}
+
+// pgRaise returns the statement raising message, an sql expression, for
+// use with \gexec so that psql stops with the message.
+func pgRaise(message string) string {
+	return fmt.Sprintf("format('do $klstr$ begin raise exception ''%%%%'', %%L; end $klstr$', %s)", message)
+}
+
+func (pgcj PGCommandJob) BuildListCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-list-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "postgres"
+	cmd := []string{
+		"--dbname=postgres",
+		"--no-align",
+		"--tuples-only",
+		"--field-separator=\t",
+		"--command=select datname, pg_get_userbyid(datdba), pg_database_size(datname) from pg_database where not datistemplate order by datname",
+	}
+	object.Spec.Template.Spec.Containers[0].Command = pgcj.getJobCommand(cmd)
+	object.Spec.Template.Spec.Containers[0].Env = pgcj.getJobEnv()
+}
+
+func (pgcj PGCommandJob) BuildDropCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-drop-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "postgres"
+	cmd := []string{
+		"--dbname=postgres",
+		fmt.Sprintf(
+			"--command=drop database %s",
+			pgIdentifier(pgcj.options.DBName),
+		),
+	}
+	object.Spec.Template.Spec.Containers[0].Command = pgcj.getJobCommand(cmd)
+	object.Spec.Template.Spec.Containers[0].Env = pgcj.getJobEnv()
+}
+
+// pgIdentifier quotes name as a postgres identifier.
+func pgIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// BuildBackupCommand dumps the database in the custom format of pg_dump,
+// which is compressed and restored with pg_restore.
+func (pgcj PGCommandJob) BuildBackupCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-backup-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "postgres"
+	object.Spec.Template.Spec.Containers[0].Env = pgcj.getJobEnv()
+	withBackupTools(object)
+	dump := fmt.Sprintf("%s --format=custom %s", pgcj.pgClient("pg_dump"), shellQuote(pgcj.options.DBName))
+	script := backupScript("pg", pgcj.options, dump, "dump")
+	object.Spec.Template.Spec.Containers[0].Command = bashCommand(script)
+}
+
+// pgClient returns the invocation of a postgres client program with the
+// credentials of the db instance, for use in shell scripts.
+func (pgcj PGCommandJob) pgClient(program string) string {
+	return fmt.Sprintf(
+		"%s --host=\"$PGHOST\" --port=\"$PGPORT\" --username=\"$PGUSERNAME\"",
+		program,
+	)
+}
+
+// BuildRestoreCommand restores the dump into the database, creating it
+// when it does not exist. Objects of the dump replace the existing ones,
+// and are owned by the instance user as the roles of the dumped instance
+// may not exist.
+func (pgcj PGCommandJob) BuildRestoreCommand(object *batchv1.Job) {
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-restore-%d", sid)
+	object.Spec.Template.Spec.Containers[0].Image = "postgres"
+	object.Spec.Template.Spec.Containers[0].Env = pgcj.getJobEnv()
+	withBackupTools(object)
+	source := pgcj.options.Restore
+	dbName := shellQuote(pgcj.options.DBName)
+	psql := pgcj.pgClient("psql") + " --set=ON_ERROR_STOP=1"
+	script, dump := restoreDump(source)
+	restore := fmt.Sprintf("%s --quiet --dbname=%s", psql, dbName)
+	expected := fmt.Sprintf("%s | %s", dump, dumpTableCount)
+	if source.Archive {
+		restore = fmt.Sprintf(
+			"%s --dbname=%s --no-owner --no-privileges --clean --if-exists --exit-on-error --verbose",
+			pgcj.pgClient("pg_restore"),
+			dbName,
+		)
+		expected = fmt.Sprintf("%s | pg_restore --list | awk '$4 == \"TABLE\"' | wc -l", dump)
+	}
+	before, after := verifyRestore(
+		expected,
+		fmt.Sprintf(
+			"%s --dbname=%s --tuples-only --no-align --command=\"select count(*) from information_schema.tables where table_schema not in ('pg_catalog', 'information_schema')\"",
+			psql,
+			dbName,
+		),
+		pgcj.options.DBName,
+	)
+	script = append(script, before...)
+	script = append(script,
+		"printf '%s\\n'",
+		shellQuote("select format('create database %I', :'db') where not exists (select 1 from pg_database where datname = :'db') \\gexec"),
+		"|",
+		fmt.Sprintf("%s --quiet --dbname=postgres --set=db=%s &&", psql, dbName),
+		fmt.Sprintf("echo %s &&", shellQuote("restoring into database "+pgcj.options.DBName)),
+		dump, "|", restore, "&&",
+	)
+	script = append(script, after...)
+	object.Spec.Template.Spec.Containers[0].Command = bashCommand(script)
+}
+
 func NewPGCommandJob(options CommandJobOptions) CommandJob {
 	return &PGCommandJob{
 		options: options,
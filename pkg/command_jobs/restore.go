@@ -0,0 +1,123 @@
+package command_jobs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// RestoreSource is the dump a restore job reads from the backup store,
+// either a backup or a dump file staged there for the restore.
+type RestoreSource struct {
+	// Backup is the key of the dump below the store prefix.
+	Backup string
+	// Gzip marks gzip compressed dumps.
+	Gzip bool
+	// Archive marks dumps in the custom format of pg_dump, which are
+	// restored with pg_restore instead of psql.
+	Archive bool
+	// Staged marks dump files staged in the store for the restore, which
+	// the restore removes once it is done.
+	Staged bool
+}
+
+// archiveMagic starts the dumps in the custom format of pg_dump.
+var archiveMagic = []byte("PGDMP")
+
+// DetectDumpFormat tells a gzip compressed dump, and a pg_dump archive
+// once decompressed, by their first bytes.
+func DetectDumpFormat(r io.Reader) (*RestoreSource, error) {
+	source := &RestoreSource{}
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		source.Gzip = true
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		br = bufio.NewReader(gr)
+	}
+	magic, err = br.Peek(len(archiveMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	source.Archive = bytes.Equal(magic, archiveMagic)
+	return source, nil
+}
+
+// restoreDump returns the setup of the restore script, and the commands
+// writing the dump to stdout.
+func restoreDump(source *RestoreSource) ([]string, string) {
+	dump := fmt.Sprintf("mc cat \"$backups\"/%s", shellQuote(source.Backup))
+	if source.Gzip {
+		dump += " | gunzip"
+	}
+	setup := mcSetup()
+	if source.Staged {
+		remove := fmt.Sprintf("mc rm --force \"$backups\"/%s > /dev/null 2>&1", shellQuote(source.Backup))
+		setup = append(setup, fmt.Sprintf("trap %s EXIT &&", shellQuote(remove)))
+	}
+	return setup, dump
+}
+
+// NewStageJob returns a job uploading the dump file it reads from stdin to
+// key below the store prefix, for restores of dump files that are not in
+// the store. The job waits for stdin to be attached.
+func NewStageJob(key string) (*batchv1.Job, error) {
+	object, err := NewJobFromTemplate()
+	if err != nil {
+		return nil, err
+	}
+	sid := time.Now().Unix()
+	object.ObjectMeta.Name = fmt.Sprintf("dbjob-stage-%d", sid)
+	container := &object.Spec.Template.Spec.Containers[0]
+	container.Name = "stage"
+	container.Image = mcImage
+	container.Env = nil
+	container.Stdin = true
+	container.StdinOnce = true
+	withBackupTools(object)
+	script := append(mcSetup(), fmt.Sprintf("mc pipe \"$backups\"/%s", shellQuote(key)))
+	container.Command = []string{"/bin/sh", "-c", strings.Join(script, " ")}
+	return object, nil
+}
+
+// dumpTableCount counts the tables created by a plain sql dump, for use
+// in a pipeline reading the dump.
+const dumpTableCount = "awk '/^CREATE TABLE /{n++} END {print n+0}'"
+
+// verifyRestore fails the restore script when count, a query counting the
+// tables of the database, finds fewer tables than expected, the number of
+// tables in the dump. The expected count is set before the restore.
+func verifyRestore(expected string, count string, dbName string) (before []string, after []string) {
+	before = []string{fmt.Sprintf("expected=$(%s) &&", expected)}
+	after = []string{
+		fmt.Sprintf("tables=$(%s) &&", count),
+		fmt.Sprintf("if [ \"$tables\" -lt \"$expected\" ]; then echo \"ERROR: the dump has $expected tables, only $tables are in \"%s >&2; exit 1; fi &&", shellQuote(dbName)),
+		fmt.Sprintf("echo \"restored $expected tables into \"%s", shellQuote(dbName)),
+	}
+	return before, after
+}
+
+// bashCommand runs script with pipefail so that a failing dump or
+// restore in a pipeline fails the job.
+func bashCommand(script []string) []string {
+	return []string{
+		"/bin/bash",
+		"-o",
+		"pipefail",
+		"-c",
+		strings.Join(script, " "),
+	}
+}
@@ -0,0 +1,173 @@
+package command_jobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectDumpFormat(t *testing.T) {
+	archive := []byte("PGDMP\x01\x0e\x00\x04\x08\x01\x01")
+	plain := []byte("--\n-- PostgreSQL database dump\n--\n")
+	tests := []struct {
+		name    string
+		dump    []byte
+		gzip    bool
+		archive bool
+	}{
+		{"plain sql", plain, false, false},
+		{"gzipped sql", gzipped(t, plain), true, false},
+		{"archive", archive, false, true},
+		{"gzipped archive", gzipped(t, archive), true, true},
+		{"short dump", []byte("x"), false, false},
+	}
+	for _, test := range tests {
+		source, err := DetectDumpFormat(bytes.NewReader(test.dump))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if source.Gzip != test.gzip || source.Archive != test.archive {
+			t.Errorf("%s: expected gzip %t and archive %t, got %t and %t", test.name, test.gzip, test.archive, source.Gzip, source.Archive)
+		}
+	}
+}
+
+func TestDetectDumpFormatBrokenGzip(t *testing.T) {
+	_, err := DetectDumpFormat(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+	if err == nil {
+		t.Error("expected an error for a broken gzip header")
+	}
+}
+
+func TestBuildRestoreCommand(t *testing.T) {
+	hostile := "o'rders; rm -rf / #"
+	tests := []struct {
+		name     string
+		dbType   string
+		dbName   string
+		source   RestoreSource
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "pg archive",
+			dbType:   "pg",
+			source:   RestoreSource{Backup: "pg/dev/orders/20181018T030000Z.dump", Archive: true},
+			contains: []string{"mc cat \"$backups\"/'pg/dev/orders/20181018T030000Z.dump' | pg_restore --list", "pg_restore --host", "--dbname='orders'"},
+			excludes: []string{"gunzip", dumpTableCount},
+		},
+		{
+			name:     "gzipped pg archive",
+			dbType:   "pg",
+			source:   RestoreSource{Backup: "uploads/restore-1", Gzip: true, Archive: true},
+			contains: []string{"mc cat \"$backups\"/'uploads/restore-1' | gunzip | pg_restore --list", "| gunzip | pg_restore --host"},
+		},
+		{
+			name:     "pg plain sql",
+			dbType:   "pg",
+			source:   RestoreSource{Backup: "uploads/restore-1"},
+			contains: []string{"mc cat \"$backups\"/'uploads/restore-1' | " + dumpTableCount, "| psql --host"},
+			excludes: []string{"pg_restore"},
+		},
+		{
+			name:     "gzipped mysql dump",
+			dbType:   "mysql",
+			source:   RestoreSource{Backup: "mysql/dev/orders/20181018T030000Z.sql.gz", Gzip: true},
+			contains: []string{"| gunzip | " + dumpTableCount, "create database if not exists `orders`", "table_schema = '\\''orders'\\''"},
+		},
+		{
+			name:     "staged dump file",
+			dbType:   "pg",
+			source:   RestoreSource{Backup: "uploads/restore-1", Staged: true},
+			contains: []string{"trap 'mc rm --force \"$backups\"/'\\''uploads/restore-1'\\'' > /dev/null 2>&1' EXIT &&"},
+		},
+		{
+			name:     "pg database needing quotes",
+			dbType:   "pg",
+			dbName:   hostile,
+			source:   RestoreSource{Backup: "pg/dev/orders/20181018T030000Z.dump", Archive: true},
+			contains: []string{"--dbname=" + shellQuote(hostile), "--set=db=" + shellQuote(hostile), "format('create database %I', :'db')"},
+		},
+		{
+			name:     "mysql database needing quotes",
+			dbType:   "mysql",
+			dbName:   hostile,
+			source:   RestoreSource{Backup: "mysql/dev/orders/20181018T030000Z.sql.gz", Gzip: true},
+			contains: []string{"| mysql --host", "\" " + shellQuote(hostile) + " &&", shellQuote("create database if not exists `" + hostile + "`")},
+		},
+	}
+	for _, test := range tests {
+		job, err := NewJobFromTemplate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dbName := test.dbName
+		if dbName == "" {
+			dbName = "orders"
+		}
+		source := test.source
+		cj, err := CreateCommandJob(test.dbType, CommandJobOptions{DBName: dbName, DBIName: "dev", Restore: &source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cj.BuildRestoreCommand(job)
+		command := job.Spec.Template.Spec.Containers[0].Command
+		script := command[len(command)-1]
+		for _, s := range test.contains {
+			if !strings.Contains(script, s) {
+				t.Errorf("%s: expected the script to contain %q, got %s", test.name, s, script)
+			}
+		}
+		for _, s := range test.excludes {
+			if strings.Contains(script, s) {
+				t.Errorf("%s: expected the script not to contain %q, got %s", test.name, s, script)
+			}
+		}
+		// the database name only appears quoted
+		if test.dbName == hostile && strings.Contains(script, hostile) {
+			t.Errorf("%s: expected the database name to be quoted, got %s", test.name, script)
+		}
+		// the expected table count is taken before the restore, and
+		// checked after it
+		if strings.Index(script, "expected=$(") > strings.Index(script, "restoring into") {
+			t.Errorf("%s: expected the table count of the dump to be taken before the restore", test.name)
+		}
+		if !strings.HasSuffix(script, "echo \"restored $expected tables into \""+shellQuote(dbName)) {
+			t.Errorf("%s: expected the script to end with the verification, got %s", test.name, script)
+		}
+		if len(job.Spec.Template.Spec.InitContainers) != 1 {
+			t.Errorf("%s: expected the minio client to be copied in", test.name)
+		}
+	}
+}
+
+func TestNewStageJob(t *testing.T) {
+	job, err := NewStageJob("uploads/restore-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if !container.Stdin || !container.StdinOnce {
+		t.Error("expected the job to read the dump file from stdin")
+	}
+	script := container.Command[len(container.Command)-1]
+	if !strings.HasSuffix(script, "mc pipe \"$backups\"/'uploads/restore-1'") {
+		t.Errorf("expected the job to upload to the quoted key, got %s", script)
+	}
+}
@@ -0,0 +1,136 @@
+// Package config reads and writes the local klstr config file, which
+// records the klstr installations a user works with.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// PathEnv overrides the location of the config file.
+const PathEnv = "KLSTR_CONFIG"
+
+// Installation is a named klstr installation: the cluster it runs on and
+// the namespace of its platform components.
+type Installation struct {
+	Name       string `json:"name"`
+	KubeConfig string `json:"kubeconfig,omitempty"`
+	Context    string `json:"context,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Domain     string `json:"domain,omitempty"`
+}
+
+type Config struct {
+	// Default names the installation commands target when no
+	// installation is given.
+	Default       string         `json:"default,omitempty"`
+	Installations []Installation `json:"installations"`
+}
+
+// DefaultPath returns $KLSTR_CONFIG, or ~/.klstr/config.yaml.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", fmt.Errorf("unable to locate the klstr config, set $HOME or $%s", PathEnv)
+	}
+	return filepath.Join(home, ".klstr", "config.yaml"), nil
+}
+
+// Load reads the config at path. A missing file is an empty config.
+func Load(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	err = yaml.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid klstr config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Save writes the config to path. The file is only readable by its owner
+// as it points at cluster credentials.
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func (c *Config) Get(name string) (*Installation, bool) {
+	for i := range c.Installations {
+		if c.Installations[i].Name == name {
+			return &c.Installations[i], true
+		}
+	}
+	return nil, false
+}
+
+// Set adds installation, replacing the installation of the same name.
+func (c *Config) Set(installation Installation) {
+	if existing, ok := c.Get(installation.Name); ok {
+		*existing = installation
+		return
+	}
+	c.Installations = append(c.Installations, installation)
+	sort.Slice(c.Installations, func(i, j int) bool {
+		return c.Installations[i].Name < c.Installations[j].Name
+	})
+}
+
+// Remove forgets the installation called name, along with it being the
+// default.
+func (c *Config) Remove(name string) bool {
+	for i := range c.Installations {
+		if c.Installations[i].Name == name {
+			c.Installations = append(c.Installations[:i], c.Installations[i+1:]...)
+			if c.Default == name {
+				c.Default = ""
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the installation called name, or the default one when
+// name is empty. It returns nil when neither is set.
+func (c *Config) Resolve(name string) (*Installation, error) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return nil, nil
+	}
+	installation, ok := c.Get(name)
+	if !ok {
+		var names []string
+		for _, i := range c.Installations {
+			names = append(names, i.Name)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown klstr installation %s, no installations are configured", name)
+		}
+		return nil, fmt.Errorf("unknown klstr installation %s, must be one of: %s", name, strings.Join(names, ", "))
+	}
+	return installation, nil
+}
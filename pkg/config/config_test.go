@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestResolveFallsBackToDefault(t *testing.T) {
+	config := &Config{}
+	config.Set(Installation{Name: "staging", Context: "gke-staging"})
+	config.Set(Installation{Name: "dev", Context: "minikube"})
+	config.Default = "dev"
+
+	installation, err := config.Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if installation.Context != "minikube" {
+		t.Errorf("expected the default installation, got %s", installation.Name)
+	}
+	installation, err = config.Resolve("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if installation.Context != "gke-staging" {
+		t.Errorf("expected staging, got %s", installation.Name)
+	}
+	if _, err = config.Resolve("prod"); err == nil {
+		t.Error("expected an unknown installation to fail")
+	}
+}
+
+func TestRemoveClearsDefault(t *testing.T) {
+	config := &Config{Default: "dev"}
+	config.Set(Installation{Name: "dev"})
+	if !config.Remove("dev") {
+		t.Fatal("expected dev to be removed")
+	}
+	if config.Default != "" {
+		t.Errorf("expected no default, got %s", config.Default)
+	}
+	installation, err := config.Resolve("")
+	if err != nil || installation != nil {
+		t.Errorf("expected no installation, got %v, %v", installation, err)
+	}
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"fmt"
+
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
+	"github.com/klstr/klstr/pkg/backing_services"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const ErrInvalidBackingService = "ErrInvalidBackingService"
+
+// ensureBackingServices provisions the services declared on a Muservice,
+// removes the ones no longer declared and returns the environment variables
+// the Muservice needs to reach them.
+func (c *Controller) ensureBackingServices(mu *klstrv1.Muservice) ([]corev1.EnvVar, error) {
+	var env []corev1.EnvVar
+	declared := make(map[string]bool)
+	for _, bs := range mu.Spec.Services {
+		backingService, err := backing_services.CreateBackingService(bs.Type, backing_services.BackingServiceOptions{
+			Name:            bs.Name,
+			ObjectName:      fmt.Sprintf("%s-%s", mu.Name, bs.Name),
+			Namespace:       mu.Namespace,
+			Labels:          map[string]string{MuserviceLabel: mu.Name},
+			OwnerReferences: muserviceOwnerReferences(mu),
+		})
+		if err != nil {
+			c.recorder.Event(mu, corev1.EventTypeWarning, ErrInvalidBackingService, err.Error())
+			return nil, err
+		}
+		for _, object := range backingService.BuildObjects() {
+			err = c.ensureBackingObject(mu, object)
+			if err != nil {
+				return nil, err
+			}
+		}
+		env = append(env, backingService.EnvVars()...)
+		declared[bs.Name] = true
+	}
+	return env, c.deleteUndeclaredBackingServices(mu, declared)
+}
+
+func (c *Controller) ensureBackingObject(mu *klstrv1.Muservice, object runtime.Object) error {
+	var existing metav1.Object
+	var err error
+	switch o := object.(type) {
+	case *corev1.PersistentVolumeClaim:
+		pi := c.kubeclientset.CoreV1().PersistentVolumeClaims(o.Namespace)
+		existing, err = pi.Get(o.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			log.Infof("creating persistent volume claim %s/%s", o.Namespace, o.Name)
+			_, err = pi.Create(o)
+			return err
+		}
+	case *corev1.Service:
+		existing, err = c.servicesLister.Services(o.Namespace).Get(o.Name)
+		if errors.IsNotFound(err) {
+			log.Infof("creating backing service %s/%s", o.Namespace, o.Name)
+			_, err = c.kubeclientset.CoreV1().Services(o.Namespace).Create(o)
+			return err
+		}
+	case *appsv1.StatefulSet:
+		si := c.kubeclientset.AppsV1().StatefulSets(o.Namespace)
+		var statefulSet *appsv1.StatefulSet
+		statefulSet, err = c.statefulSetLister.StatefulSets(o.Namespace).Get(o.Name)
+		if errors.IsNotFound(err) {
+			log.Infof("creating statefulset %s/%s", o.Namespace, o.Name)
+			_, err = si.Create(o)
+			return err
+		}
+		if err == nil && metav1.IsControlledBy(statefulSet, mu) &&
+			!equality.Semantic.DeepDerivative(o.Spec, statefulSet.Spec) {
+			log.Infof("updating drifted statefulset %s/%s", o.Namespace, o.Name)
+			updated := statefulSet.DeepCopy()
+			updated.Spec.Replicas = o.Spec.Replicas
+			updated.Spec.Template = o.Spec.Template
+			_, err = si.Update(updated)
+			return err
+		}
+		existing = statefulSet
+	default:
+		return fmt.Errorf("unsupported backing service object %T", object)
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(existing, mu) {
+		msg := fmt.Sprintf(MessageResourceExists, existing.GetName())
+		c.recorder.Event(mu, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// deleteUndeclaredBackingServices removes backing services that were
+// dropped from the Muservice spec. Backing services of deleted Muservices
+// are garbage collected through their owner references.
+func (c *Controller) deleteUndeclaredBackingServices(mu *klstrv1.Muservice, declared map[string]bool) error {
+	selector, err := labels.Parse(fmt.Sprintf("%s=%s,%s", MuserviceLabel, mu.Name, backing_services.BackingServiceLabel))
+	if err != nil {
+		return err
+	}
+	deleteOptions := &metav1.DeleteOptions{}
+
+	statefulSets, err := c.statefulSetLister.StatefulSets(mu.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	for _, o := range statefulSets {
+		if declared[o.Labels[backing_services.BackingServiceLabel]] || !metav1.IsControlledBy(o, mu) {
+			continue
+		}
+		log.Infof("deleting statefulset %s/%s", o.Namespace, o.Name)
+		err = c.kubeclientset.AppsV1().StatefulSets(o.Namespace).Delete(o.Name, deleteOptions)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	services, err := c.servicesLister.Services(mu.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	for _, o := range services {
+		if declared[o.Labels[backing_services.BackingServiceLabel]] || !metav1.IsControlledBy(o, mu) {
+			continue
+		}
+		log.Infof("deleting backing service %s/%s", o.Namespace, o.Name)
+		err = c.kubeclientset.CoreV1().Services(o.Namespace).Delete(o.Name, deleteOptions)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	claims, err := c.claimsLister.PersistentVolumeClaims(mu.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	for _, o := range claims {
+		if declared[o.Labels[backing_services.BackingServiceLabel]] || !metav1.IsControlledBy(o, mu) {
+			continue
+		}
+		log.Infof("deleting persistent volume claim %s/%s", o.Namespace, o.Name)
+		err = c.kubeclientset.CoreV1().PersistentVolumeClaims(o.Namespace).Delete(o.Name, deleteOptions)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,15 +1,220 @@
 package controller
 
 import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	clientset "github.com/klstr/klstr/pkg/client/clientset/versioned"
+	klstrscheme "github.com/klstr/klstr/pkg/client/clientset/versioned/scheme"
+	informers "github.com/klstr/klstr/pkg/client/informers/externalversions"
+	listers "github.com/klstr/klstr/pkg/client/listers/klstr/v1"
+	"github.com/klstr/klstr/pkg/util"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	extnlisters "k8s.io/client-go/listers/extensions/v1beta1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
-func SetupController() error {
+const controllerAgentName = "klstr-controller"
+
+const resyncPeriod = 30 * time.Second
+
+// Controller reconciles Muservice objects into the deployments, services
+// and ingresses that run and expose them, along with their backing services.
+type Controller struct {
+	kubeclientset  kubernetes.Interface
+	klstrclientset clientset.Interface
+	// namespace is the klstr namespace, where db instances are registered
+	// and where the jobs provisioning databases run.
+	namespace string
+
+	deploymentsLister appslisters.DeploymentLister
+	deploymentsSynced cache.InformerSynced
+	statefulSetLister appslisters.StatefulSetLister
+	statefulSetSynced cache.InformerSynced
+	servicesLister    corelisters.ServiceLister
+	servicesSynced    cache.InformerSynced
+	ingressesLister   extnlisters.IngressLister
+	ingressesSynced   cache.InformerSynced
+	claimsLister      corelisters.PersistentVolumeClaimLister
+	claimsSynced      cache.InformerSynced
+	muservicesLister  listers.MuserviceLister
+	muservicesSynced  cache.InformerSynced
+
+	workqueue workqueue.RateLimitingInterface
+	recorder  record.EventRecorder
+}
+
+func NewController(
+	kubeclientset kubernetes.Interface,
+	klstrclientset clientset.Interface,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	klstrInformerFactory informers.SharedInformerFactory,
+	namespace string,
+) *Controller {
+	deploymentInformer := kubeInformerFactory.Apps().V1().Deployments()
+	statefulSetInformer := kubeInformerFactory.Apps().V1().StatefulSets()
+	serviceInformer := kubeInformerFactory.Core().V1().Services()
+	ingressInformer := kubeInformerFactory.Extensions().V1beta1().Ingresses()
+	claimInformer := kubeInformerFactory.Core().V1().PersistentVolumeClaims()
+	muserviceInformer := klstrInformerFactory.Klstr().V1().Muservices()
+
+	klstrscheme.AddToScheme(scheme.Scheme)
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(log.Infof)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: kubeclientset.CoreV1().Events(""),
+	})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
+
+	c := &Controller{
+		kubeclientset:     kubeclientset,
+		klstrclientset:    klstrclientset,
+		namespace:         namespace,
+		deploymentsLister: deploymentInformer.Lister(),
+		deploymentsSynced: deploymentInformer.Informer().HasSynced,
+		statefulSetLister: statefulSetInformer.Lister(),
+		statefulSetSynced: statefulSetInformer.Informer().HasSynced,
+		servicesLister:    serviceInformer.Lister(),
+		servicesSynced:    serviceInformer.Informer().HasSynced,
+		ingressesLister:   ingressInformer.Lister(),
+		ingressesSynced:   ingressInformer.Informer().HasSynced,
+		claimsLister:      claimInformer.Lister(),
+		claimsSynced:      claimInformer.Informer().HasSynced,
+		muservicesLister:  muserviceInformer.Lister(),
+		muservicesSynced:  muserviceInformer.Informer().HasSynced,
+		workqueue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "Muservices"),
+		recorder:          recorder,
+	}
+
+	muserviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueMuservice,
+		UpdateFunc: func(old, new interface{}) {
+			c.enqueueMuservice(new)
+		},
+	})
+	ownedHandler := cache.ResourceEventHandlerFuncs{
+		AddFunc: c.handleObject,
+		UpdateFunc: func(old, new interface{}) {
+			if new.(metav1.Object).GetResourceVersion() == old.(metav1.Object).GetResourceVersion() {
+				return
+			}
+			c.handleObject(new)
+		},
+		DeleteFunc: c.handleObject,
+	}
+	deploymentInformer.Informer().AddEventHandler(ownedHandler)
+	statefulSetInformer.Informer().AddEventHandler(ownedHandler)
+	serviceInformer.Informer().AddEventHandler(ownedHandler)
+	ingressInformer.Informer().AddEventHandler(ownedHandler)
+	return c
+}
+
+// Run starts the workers and blocks until stopCh is closed.
+func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer utilruntime.HandleCrash()
+	defer c.workqueue.ShutDown()
+
+	log.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(stopCh, c.deploymentsSynced, c.statefulSetSynced, c.servicesSynced, c.ingressesSynced, c.claimsSynced, c.muservicesSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+
+	log.Infof("Starting %d workers", threadiness)
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+	<-stopCh
+	log.Info("Shutting down workers")
+	return nil
+}
+
+func (c *Controller) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	obj, shutdown := c.workqueue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.workqueue.Done(obj)
+
+	key, ok := obj.(string)
+	if !ok {
+		c.workqueue.Forget(obj)
+		utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
+		return true
+	}
+	if err := c.syncHandler(key); err != nil {
+		log.Errorf("error syncing muservice %s: %v", key, err)
+		c.workqueue.AddRateLimited(key)
+		return true
+	}
+	c.workqueue.Forget(obj)
+	return true
+}
+
+// enqueueMuservice queues obj right away for informer events. Failed syncs
+// are requeued with backoff by processNextWorkItem instead.
+func (c *Controller) enqueueMuservice(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.workqueue.Add(key)
+}
+
+// handleObject enqueues the Muservice owning a deployment, statefulset,
+// service or ingress so that deletes and manual edits of owned objects get
+// corrected.
+func (c *Controller) handleObject(obj interface{}) {
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		object, ok = tombstone.Obj.(metav1.Object)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+	}
+	ownerRef := metav1.GetControllerOf(object)
+	if ownerRef == nil || ownerRef.Kind != "Muservice" {
+		return
+	}
+	mu, err := c.muservicesLister.Muservices(object.GetNamespace()).Get(ownerRef.Name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			utilruntime.HandleError(err)
+		}
+		return
+	}
+	c.enqueueMuservice(mu)
+}
+
+func SetupController(namespace string) error {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return err
@@ -18,13 +223,33 @@ func SetupController() error {
 	if err != nil {
 		return err
 	}
-	for {
-		podList, err := cs.CoreV1().Pods("default").List(metav1.ListOptions{})
-		if err != nil {
-			return err
-		}
-		log.Infof("Found %d pods.", len(podList.Items))
-		log.Infof("Pods : %v", podList.Items)
-		time.Sleep(10 * time.Second)
+	kcs, err := clientset.NewForConfig(config)
+	if err != nil {
+		return err
 	}
+	namespace, err = util.ResolveNamespace(cs, namespace)
+	if err != nil {
+		return err
+	}
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(cs, resyncPeriod)
+	klstrInformerFactory := informers.NewSharedInformerFactory(kcs, resyncPeriod)
+	controller := NewController(cs, kcs, kubeInformerFactory, klstrInformerFactory, namespace)
+
+	stopCh := setupSignalHandler()
+	kubeInformerFactory.Start(stopCh)
+	klstrInformerFactory.Start(stopCh)
+	return controller.Run(2, stopCh)
+}
+
+func setupSignalHandler() <-chan struct{} {
+	stopCh := make(chan struct{})
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		close(stopCh)
+		<-sigCh
+		os.Exit(1)
+	}()
+	return stopCh
 }
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
+	"github.com/klstr/klstr/pkg/command_jobs"
+	"github.com/klstr/klstr/pkg/util"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ErrInvalidDatabase   = "ErrInvalidDatabase"
+	ErrDatabaseJobFailed = "ErrDatabaseJobFailed"
+)
+
+// ensureDatabases provisions a database and a dedicated user for every
+// database declared on a Muservice, and returns the environment variables
+// referencing the per-service secret holding the credentials.
+func (c *Controller) ensureDatabases(mu *klstrv1.Muservice) ([]corev1.EnvVar, error) {
+	var env []corev1.EnvVar
+	for _, db := range mu.Spec.Databases {
+		dbType, ok := util.DatabaseTypes[db.Type]
+		if !ok {
+			err := fmt.Errorf("invalid database type %s for database %s", db.Type, db.Name)
+			c.recorder.Event(mu, corev1.EventTypeWarning, ErrInvalidDatabase, err.Error())
+			return nil, err
+		}
+		secret, err := c.ensureDatabaseSecret(mu, db, dbType)
+		if err != nil {
+			return nil, err
+		}
+		err = c.ensureDatabaseJob(mu, db, dbType, secret)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, databaseEnvVars(db, dbType, secret.Name)...)
+	}
+	return env, nil
+}
+
+func (c *Controller) ensureDatabaseSecret(
+	mu *klstrv1.Muservice,
+	db klstrv1.Database,
+	dbType util.DatabaseType,
+) (*corev1.Secret, error) {
+	si := c.kubeclientset.CoreV1().Secrets(mu.Namespace)
+	name := fmt.Sprintf("%s-%s-db", mu.Name, db.Name)
+	secret, err := si.Get(name, metav1.GetOptions{})
+	if err == nil {
+		if !metav1.IsControlledBy(secret, mu) {
+			msg := fmt.Sprintf(MessageResourceExists, name)
+			c.recorder.Event(mu, corev1.EventTypeWarning, ErrResourceExists, msg)
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return secret, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	dbiSecretName := fmt.Sprintf("dbi-%s-%s", dbType.CommandJob, db.Instance)
+	dbiSecret, err := c.kubeclientset.CoreV1().Secrets(c.namespace).Get(dbiSecretName, metav1.GetOptions{})
+	if err != nil {
+		msg := fmt.Sprintf("db instance %s of type %s is not registered", db.Instance, db.Type)
+		c.recorder.Event(mu, corev1.EventTypeWarning, ErrInvalidDatabase, msg)
+		return nil, err
+	}
+	password, err := util.GeneratePassword(24)
+	if err != nil {
+		return nil, err
+	}
+	host := string(dbiSecret.Data["host"])
+	port := string(dbiSecret.Data["port"])
+	user := databaseUser(mu, db)
+	labels := muserviceLabels(mu)
+	labels[util.DatabaseLabel] = db.Name
+	labels[util.DBInstanceLabel] = db.Instance
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       mu.Namespace,
+			Labels:          labels,
+			OwnerReferences: muserviceOwnerReferences(mu),
+		},
+		StringData: map[string]string{
+			"uri":      fmt.Sprintf("%s://%s:%s@%s:%s/%s", dbType.Scheme, user, password, host, port, db.Name),
+			"host":     host,
+			"port":     port,
+			"database": db.Name,
+			"user":     user,
+			"password": password,
+		},
+	}
+	log.Infof("creating database secret %s/%s", mu.Namespace, name)
+	return si.Create(secret)
+}
+
+// ensureDatabaseJob creates the job provisioning the database and its user
+// once. The job name is derived from the database so that resyncs find the
+// existing job instead of provisioning the database again.
+func (c *Controller) ensureDatabaseJob(
+	mu *klstrv1.Muservice,
+	db klstrv1.Database,
+	dbType util.DatabaseType,
+	secret *corev1.Secret,
+) error {
+	ji := c.kubeclientset.BatchV1().Jobs(c.namespace)
+	jobName := databaseJobName(mu, db)
+	job, err := ji.Get(jobName, metav1.GetOptions{})
+	if err == nil {
+		if job.Status.Failed > 0 && job.Status.Succeeded == 0 {
+			msg := fmt.Sprintf("job %s provisioning database %s failed", jobName, db.Name)
+			c.recorder.Event(mu, corev1.EventTypeWarning, ErrDatabaseJobFailed, msg)
+		}
+		if job.Status.Succeeded > 0 || job.Status.Failed > 0 {
+			return nil
+		}
+		return c.ensureDatabaseJobSecret(job, secret)
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	job, err = command_jobs.NewJobFromTemplate()
+	if err != nil {
+		return err
+	}
+	cj, err := command_jobs.CreateCommandJob(dbType.CommandJob, command_jobs.CommandJobOptions{
+		DBName:         db.Name,
+		DBIName:        db.Instance,
+		Username:       string(secret.Data["user"]),
+		PasswordSecret: jobName,
+		Owner:          databaseOwner(mu, db),
+	})
+	if err != nil {
+		return err
+	}
+	cj.BuildCreateWithUserCommand(job)
+	job.Name = jobName
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[MuserviceLabel] = mu.Name
+	job.Labels[util.DatabaseLabel] = db.Name
+	job.Labels[util.DBInstanceLabel] = db.Instance
+	log.Infof("creating job %s to provision database %s", jobName, db.Name)
+	job, err = ji.Create(job)
+	if err != nil {
+		return err
+	}
+	return c.ensureDatabaseJobSecret(job, secret)
+}
+
+// ensureDatabaseJobSecret copies the password of the database user next to
+// the job provisioning it, as the job cannot read the secret of the
+// Muservice namespace. The copy is owned by the job, and its pod waits for
+// the copy to exist.
+func (c *Controller) ensureDatabaseJobSecret(job *batchv1.Job, secret *corev1.Secret) error {
+	si := c.kubeclientset.CoreV1().Secrets(c.namespace)
+	_, err := si.Get(job.Name, metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	_, err = si.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   job.Name,
+			Labels: job.Labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(job, batchv1.SchemeGroupVersion.WithKind("Job")),
+			},
+		},
+		Data: map[string][]byte{"password": secret.Data["password"]},
+	})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func databaseEnvVars(db klstrv1.Database, dbType util.DatabaseType, secretName string) []corev1.EnvVar {
+	secretEnv := func(name, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					Key: key,
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: secretName,
+					},
+				},
+			},
+		}
+	}
+	return []corev1.EnvVar{
+		secretEnv(util.EnvVarName(db.Name, "DATABASE_URI"), "uri"),
+		secretEnv(util.EnvVarName(db.Name, dbType.EnvPrefix, "HOST"), "host"),
+		secretEnv(util.EnvVarName(db.Name, dbType.EnvPrefix, "PORT"), "port"),
+		secretEnv(util.EnvVarName(db.Name, dbType.EnvPrefix, "USER"), "user"),
+		secretEnv(util.EnvVarName(db.Name, dbType.EnvPrefix, "PASSWORD"), "password"),
+	}
+}
+
+// databaseUser returns the user owning a Muservice database. The hash of
+// the database owner keeps the users of different Muservices apart, and
+// the name within the 32 character limit on mysql user names.
+func databaseUser(mu *klstrv1.Muservice, db klstrv1.Database) string {
+	user := strings.ToLower(strings.Replace(fmt.Sprintf("%s_%s", mu.Name, db.Name), "-", "_", -1))
+	if len(user) > 21 {
+		user = user[:21]
+	}
+	sum := sha1.Sum([]byte(databaseOwner(mu, db)))
+	return fmt.Sprintf("%s_%x", user, sum[:5])
+}
+
+// databaseOwner identifies the Muservice database a user was created for.
+func databaseOwner(mu *klstrv1.Muservice, db klstrv1.Database) string {
+	return fmt.Sprintf("%s/%s/%s/%s", mu.Namespace, mu.Name, db.Instance, db.Name)
+}
+
+func databaseJobName(mu *klstrv1.Muservice, db klstrv1.Database) string {
+	sum := sha1.Sum([]byte(databaseOwner(mu, db)))
+	return fmt.Sprintf("dbjob-provision-%x", sum[:5])
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"fmt"
+
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	extnv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	ErrInvalidExpose = "ErrInvalidExpose"
+	IngressClass     = "nginx"
+)
+
+func (c *Controller) ensureIngress(mu *klstrv1.Muservice) (*extnv1beta1.Ingress, error) {
+	ii := c.kubeclientset.ExtensionsV1beta1().Ingresses(mu.Namespace)
+	ingress, err := c.ingressesLister.Ingresses(mu.Namespace).Get(mu.Name)
+	if len(mu.Spec.Expose) == 0 {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if metav1.IsControlledBy(ingress, mu) {
+			log.Infof("deleting ingress for unexposed muservice %s/%s", mu.Namespace, mu.Name)
+			return nil, ii.Delete(ingress.Name, &metav1.DeleteOptions{})
+		}
+		return nil, nil
+	}
+	desired, buildErr := newIngress(mu)
+	if buildErr != nil {
+		c.recorder.Event(mu, corev1.EventTypeWarning, ErrInvalidExpose, buildErr.Error())
+		return nil, buildErr
+	}
+	if errors.IsNotFound(err) {
+		log.Infof("creating ingress for muservice %s/%s", mu.Namespace, mu.Name)
+		return ii.Create(desired)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(ingress, mu) {
+		msg := fmt.Sprintf(MessageResourceExists, ingress.Name)
+		c.recorder.Event(mu, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return nil, fmt.Errorf("%s", msg)
+	}
+	if equality.Semantic.DeepEqual(desired.Spec, ingress.Spec) && hasAnnotations(ingress, desired.Annotations) {
+		return ingress, nil
+	}
+	log.Infof("updating ingress for muservice %s/%s", mu.Namespace, mu.Name)
+	updated := ingress.DeepCopy()
+	updated.Labels = desired.Labels
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	for key, value := range desired.Annotations {
+		updated.Annotations[key] = value
+	}
+	updated.Spec = desired.Spec
+	return ii.Update(updated)
+}
+
+// hasAnnotations tells whether the ingress carries the annotations. Other
+// annotations of the ingress, such as those added by other controllers,
+// are left alone.
+func hasAnnotations(ingress *extnv1beta1.Ingress, annotations map[string]string) bool {
+	for key, value := range annotations {
+		existing, ok := ingress.Annotations[key]
+		if !ok || existing != value {
+			return false
+		}
+	}
+	return true
+}
+
+func newIngress(mu *klstrv1.Muservice) (*extnv1beta1.Ingress, error) {
+	var rules []extnv1beta1.IngressRule
+	for _, expose := range mu.Spec.Expose {
+		if expose.Host == "" {
+			return nil, fmt.Errorf("expose rule of muservice %s has no host", mu.Name)
+		}
+		port := expose.Port
+		if port == 0 {
+			if len(mu.Spec.Ports) == 0 {
+				return nil, fmt.Errorf("muservice %s exposes %s but declares no ports", mu.Name, expose.Host)
+			}
+			port = mu.Spec.Ports[0].Port
+		}
+		paths := expose.Paths
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		var httpPaths []extnv1beta1.HTTPIngressPath
+		for _, path := range paths {
+			httpPaths = append(httpPaths, extnv1beta1.HTTPIngressPath{
+				Path: path,
+				Backend: extnv1beta1.IngressBackend{
+					ServiceName: mu.Name,
+					ServicePort: intstr.FromInt(int(port)),
+				},
+			})
+		}
+		rules = append(rules, extnv1beta1.IngressRule{
+			Host: expose.Host,
+			IngressRuleValue: extnv1beta1.IngressRuleValue{
+				HTTP: &extnv1beta1.HTTPIngressRuleValue{Paths: httpPaths},
+			},
+		})
+	}
+	return &extnv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            mu.Name,
+			Namespace:       mu.Namespace,
+			Labels:          muserviceLabels(mu),
+			Annotations:     map[string]string{"kubernetes.io/ingress.class": IngressClass},
+			OwnerReferences: muserviceOwnerReferences(mu),
+		},
+		Spec: extnv1beta1.IngressSpec{Rules: rules},
+	}, nil
+}
+
+func ingressAddresses(ingress *extnv1beta1.Ingress) []string {
+	if ingress == nil {
+		return nil
+	}
+	var addresses []string
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			addresses = append(addresses, lb.Hostname)
+		} else if lb.IP != "" {
+			addresses = append(addresses, lb.IP)
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"fmt"
+
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extnv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+const MuserviceLabel = "io.klstr/muservice"
+
+const (
+	SuccessSynced         = "Synced"
+	ErrResourceExists     = "ErrResourceExists"
+	MessageResourceExists = "Resource %q already exists and is not managed by Muservice"
+)
+
+var muserviceKind = klstrv1.SchemeGroupVersion.WithKind("Muservice")
+
+func (c *Controller) syncHandler(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+	mu, err := c.muservicesLister.Muservices(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Infof("muservice %s no longer exists", key)
+			return nil
+		}
+		return err
+	}
+
+	env, err := c.ensureBackingServices(mu)
+	if err != nil {
+		return err
+	}
+	dbEnv, err := c.ensureDatabases(mu)
+	if err != nil {
+		return err
+	}
+	env = append(env, dbEnv...)
+	tracingEnv, err := c.tracingEnv(mu)
+	if err != nil {
+		return err
+	}
+	env = append(env, tracingEnv...)
+	deployment, err := c.ensureDeployment(mu, env)
+	if err != nil {
+		return err
+	}
+	err = c.ensureService(mu)
+	if err != nil {
+		return err
+	}
+	ingress, err := c.ensureIngress(mu)
+	if err != nil {
+		return err
+	}
+	err = c.updateMuserviceStatus(mu, deployment, ingress)
+	if err != nil {
+		return err
+	}
+	c.recorder.Event(mu, corev1.EventTypeNormal, SuccessSynced, "Muservice synced successfully")
+	return nil
+}
+
+func (c *Controller) ensureDeployment(mu *klstrv1.Muservice, env []corev1.EnvVar) (*appsv1.Deployment, error) {
+	di := c.kubeclientset.AppsV1().Deployments(mu.Namespace)
+	desired := newDeployment(mu, env)
+	deployment, err := c.deploymentsLister.Deployments(mu.Namespace).Get(mu.Name)
+	if errors.IsNotFound(err) {
+		log.Infof("creating deployment for muservice %s/%s", mu.Namespace, mu.Name)
+		return di.Create(desired)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(deployment, mu) {
+		msg := fmt.Sprintf(MessageResourceExists, deployment.Name)
+		c.recorder.Event(mu, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return nil, fmt.Errorf("%s", msg)
+	}
+	if equality.Semantic.DeepDerivative(desired.Spec, deployment.Spec) {
+		return deployment, nil
+	}
+	log.Infof("updating drifted deployment for muservice %s/%s", mu.Namespace, mu.Name)
+	updated := deployment.DeepCopy()
+	updated.Labels = desired.Labels
+	updated.Spec = desired.Spec
+	return di.Update(updated)
+}
+
+func (c *Controller) ensureService(mu *klstrv1.Muservice) error {
+	si := c.kubeclientset.CoreV1().Services(mu.Namespace)
+	service, err := c.servicesLister.Services(mu.Namespace).Get(mu.Name)
+	if len(mu.Spec.Ports) == 0 {
+		if err == nil && metav1.IsControlledBy(service, mu) {
+			log.Infof("deleting service for muservice %s/%s without ports", mu.Namespace, mu.Name)
+			return si.Delete(service.Name, &metav1.DeleteOptions{})
+		}
+		return nil
+	}
+	desired := newService(mu)
+	if errors.IsNotFound(err) {
+		log.Infof("creating service for muservice %s/%s", mu.Namespace, mu.Name)
+		_, err = si.Create(desired)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(service, mu) {
+		msg := fmt.Sprintf(MessageResourceExists, service.Name)
+		c.recorder.Event(mu, corev1.EventTypeWarning, ErrResourceExists, msg)
+		return fmt.Errorf("%s", msg)
+	}
+	if equality.Semantic.DeepDerivative(desired.Spec, service.Spec) {
+		return nil
+	}
+	log.Infof("updating drifted service for muservice %s/%s", mu.Namespace, mu.Name)
+	updated := service.DeepCopy()
+	updated.Labels = desired.Labels
+	desired.Spec.ClusterIP = service.Spec.ClusterIP
+	updated.Spec = desired.Spec
+	_, err = si.Update(updated)
+	return err
+}
+
+func (c *Controller) updateMuserviceStatus(
+	mu *klstrv1.Muservice,
+	deployment *appsv1.Deployment,
+	ingress *extnv1beta1.Ingress,
+) error {
+	status := klstrv1.MuserviceStatus{
+		ObservedGeneration: mu.Generation,
+		Replicas:           deployment.Status.Replicas,
+		AvailableReplicas:  deployment.Status.AvailableReplicas,
+		IngressAddresses:   ingressAddresses(ingress),
+	}
+	if equality.Semantic.DeepEqual(status, mu.Status) {
+		return nil
+	}
+	muCopy := mu.DeepCopy()
+	muCopy.Status = status
+	_, err := c.klstrclientset.KlstrV1().Muservices(mu.Namespace).UpdateStatus(muCopy)
+	return err
+}
+
+func muserviceLabels(mu *klstrv1.Muservice) map[string]string {
+	return map[string]string{
+		"app":          mu.Name,
+		MuserviceLabel: mu.Name,
+	}
+}
+
+func muserviceOwnerReferences(mu *klstrv1.Muservice) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(mu, muserviceKind),
+	}
+}
+
+// newDeployment builds the deployment for a Muservice. env holds the
+// variables pointing at klstr managed services and is appended to the
+// environment declared on the Muservice.
+func newDeployment(mu *klstrv1.Muservice, env []corev1.EnvVar) *appsv1.Deployment {
+	labels := muserviceLabels(mu)
+	replicas := int32(1)
+	if mu.Spec.Replicas != nil {
+		replicas = *mu.Spec.Replicas
+	}
+	var containerEnv []corev1.EnvVar
+	containerEnv = append(containerEnv, mu.Spec.Environment...)
+	containerEnv = append(containerEnv, env...)
+	var ports []corev1.ContainerPort
+	for _, p := range mu.Spec.Ports {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          p.Name,
+			ContainerPort: p.Port,
+			Protocol:      p.Protocol,
+		})
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            mu.Name,
+			Namespace:       mu.Namespace,
+			Labels:          labels,
+			OwnerReferences: muserviceOwnerReferences(mu),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{MuserviceLabel: mu.Name},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  mu.Name,
+							Image: mu.Spec.Image,
+							Ports: ports,
+							Env:   containerEnv,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newService(mu *klstrv1.Muservice) *corev1.Service {
+	var ports []corev1.ServicePort
+	for i, p := range mu.Spec.Ports {
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("port-%d", i)
+		}
+		ports = append(ports, corev1.ServicePort{
+			Name:       name,
+			Port:       p.Port,
+			TargetPort: intstr.FromInt(int(p.Port)),
+			Protocol:   p.Protocol,
+		})
+	}
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            mu.Name,
+			Namespace:       mu.Namespace,
+			Labels:          muserviceLabels(mu),
+			OwnerReferences: muserviceOwnerReferences(mu),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{MuserviceLabel: mu.Name},
+			Ports:    ports,
+		},
+	}
+}
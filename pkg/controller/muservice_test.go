@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"testing"
+
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
+	"github.com/klstr/klstr/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestMuservice() *klstrv1.Muservice {
+	return &klstrv1.Muservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "muservice",
+			Namespace: "default",
+			UID:       "1234",
+		},
+		Spec: klstrv1.MuserviceSpec{
+			Image: "quay.io/klstr/muservice:v0.1.0",
+			Ports: []klstrv1.MuservicePort{
+				{Port: 4323},
+			},
+		},
+	}
+}
+
+func TestNewDeploymentDefaultsReplicas(t *testing.T) {
+	mu := newTestMuservice()
+	deployment := newDeployment(mu, nil)
+	if *deployment.Spec.Replicas != 1 {
+		t.Errorf("expected 1 replica, got %d", *deployment.Spec.Replicas)
+	}
+	if !metav1.IsControlledBy(deployment, mu) {
+		t.Error("deployment is not controlled by muservice")
+	}
+	container := deployment.Spec.Template.Spec.Containers[0]
+	if container.Image != mu.Spec.Image {
+		t.Errorf("expected image %s, got %s", mu.Spec.Image, container.Image)
+	}
+	if container.Ports[0].ContainerPort != 4323 {
+		t.Errorf("expected container port 4323, got %d", container.Ports[0].ContainerPort)
+	}
+}
+
+func TestNewServiceNamesPorts(t *testing.T) {
+	mu := newTestMuservice()
+	service := newService(mu)
+	if service.Spec.Ports[0].Name != "port-0" {
+		t.Errorf("expected port name port-0, got %s", service.Spec.Ports[0].Name)
+	}
+	if service.Spec.Ports[0].TargetPort.IntValue() != 4323 {
+		t.Errorf("expected target port 4323, got %s", service.Spec.Ports[0].TargetPort.String())
+	}
+	if service.Spec.Selector[MuserviceLabel] != mu.Name {
+		t.Error("service does not select muservice pods")
+	}
+}
+
+func TestNewIngressDefaultsPathAndPort(t *testing.T) {
+	mu := newTestMuservice()
+	mu.Spec.Expose = klstrv1.Expose{
+		{Host: "muservice.minikube.local"},
+		{Host: "api.minikube.local", Paths: []string{"/v1", "/v2"}, Port: 8080},
+	}
+	ingress, err := newIngress(mu)
+	if err != nil {
+		t.Fatal("error building ingress ", err)
+	}
+	if len(ingress.Spec.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(ingress.Spec.Rules))
+	}
+	first := ingress.Spec.Rules[0].HTTP.Paths
+	if len(first) != 1 || first[0].Path != "/" || first[0].Backend.ServicePort.IntValue() != 4323 {
+		t.Errorf("first rule not defaulted correctly: %+v", first)
+	}
+	second := ingress.Spec.Rules[1].HTTP.Paths
+	if len(second) != 2 || second[1].Backend.ServicePort.IntValue() != 8080 {
+		t.Errorf("second rule not built correctly: %+v", second)
+	}
+}
+
+func TestNewIngressRequiresPort(t *testing.T) {
+	mu := newTestMuservice()
+	mu.Spec.Ports = nil
+	mu.Spec.Expose = klstrv1.Expose{{Host: "muservice.minikube.local"}}
+	_, err := newIngress(mu)
+	if err == nil {
+		t.Error("expected error when exposing a muservice without ports")
+	}
+}
+
+func TestHasAnnotationsIgnoresOtherAnnotations(t *testing.T) {
+	mu := newTestMuservice()
+	mu.Spec.Expose = klstrv1.Expose{{Host: "muservice.minikube.local"}}
+	desired, err := newIngress(mu)
+	if err != nil {
+		t.Fatal("error building ingress ", err)
+	}
+	existing := desired.DeepCopy()
+	existing.Annotations["cert-manager.io/cluster-issuer"] = "letsencrypt"
+	if !hasAnnotations(existing, desired.Annotations) {
+		t.Error("expected annotations added by others to be ignored")
+	}
+	existing.Annotations["kubernetes.io/ingress.class"] = "traefik"
+	if hasAnnotations(existing, desired.Annotations) {
+		t.Error("expected a changed ingress class to be reconciled")
+	}
+}
+
+func TestDatabaseEnvVars(t *testing.T) {
+	db := klstrv1.Database{Name: "mysampledb", Type: "postgres", Instance: "dev"}
+	env := databaseEnvVars(db, util.DatabaseTypes[db.Type], "muservice-mysampledb-db")
+	expected := []string{
+		"MYSAMPLEDB_DATABASE_URI",
+		"MYSAMPLEDB_PG_HOST",
+		"MYSAMPLEDB_PG_PORT",
+		"MYSAMPLEDB_PG_USER",
+		"MYSAMPLEDB_PG_PASSWORD",
+	}
+	for i, name := range expected {
+		if env[i].Name != name {
+			t.Errorf("expected env var %s, got %s", name, env[i].Name)
+		}
+		if env[i].ValueFrom.SecretKeyRef.Name != "muservice-mysampledb-db" {
+			t.Errorf("env var %s does not reference the database secret", env[i].Name)
+		}
+	}
+}
+
+func TestDatabaseJobNameIsStable(t *testing.T) {
+	mu := newTestMuservice()
+	db := klstrv1.Database{Name: "mysampledb", Type: "postgres", Instance: "dev"}
+	if databaseJobName(mu, db) != databaseJobName(mu, db) {
+		t.Error("database job name is not stable")
+	}
+	other := klstrv1.Database{Name: "otherdb", Type: "postgres", Instance: "dev"}
+	if databaseJobName(mu, db) == databaseJobName(mu, other) {
+		t.Error("database job names collide")
+	}
+}
+
+func TestDatabaseUserIsUniquePerDatabase(t *testing.T) {
+	mu := newTestMuservice()
+	db := klstrv1.Database{Name: "mysampledb", Type: "postgres", Instance: "dev"}
+	user := databaseUser(mu, db)
+	if len(user) > 32 {
+		t.Errorf("database user %s is longer than 32 characters", user)
+	}
+	if user != databaseUser(mu, db) {
+		t.Error("database user is not stable")
+	}
+	other := newTestMuservice()
+	other.Namespace = "staging"
+	dashed := newTestMuservice()
+	dashed.Name = "muservice-mysampledb"
+	long := newTestMuservice()
+	long.Name = "muservice-with-a-rather-long-name"
+	longer := newTestMuservice()
+	longer.Name = "muservice-with-a-rather-long-name-too"
+	users := map[string]string{
+		"namespace": databaseUser(other, db),
+		"instance":  databaseUser(mu, klstrv1.Database{Name: "mysampledb", Type: "postgres", Instance: "prod"}),
+		"dashes":    databaseUser(dashed, klstrv1.Database{Name: "", Type: "postgres", Instance: "dev"}),
+		"long":      databaseUser(long, db),
+		"longer":    databaseUser(longer, db),
+	}
+	seen := map[string]string{user: "muservice"}
+	for name, u := range users {
+		if len(u) > 32 {
+			t.Errorf("%s: database user %s is longer than 32 characters", name, u)
+		}
+		if previous, ok := seen[u]; ok {
+			t.Errorf("%s: database user %s collides with %s", name, u, previous)
+		}
+		seen[u] = name
+	}
+}
+
+func TestJaegerEnvVarsKeepsDeclaredVariables(t *testing.T) {
+	mu := newTestMuservice()
+	mu.Spec.Environment = []corev1.EnvVar{
+		{Name: "JAEGER_ENDPOINT", Value: "http://collector:14268/api/traces"},
+	}
+	env := map[string]string{}
+	for _, e := range jaegerEnvVars(mu, "klstr-system") {
+		env[e.Name] = e.Value
+	}
+	if _, ok := env["JAEGER_ENDPOINT"]; ok {
+		t.Error("declared JAEGER_ENDPOINT was overridden")
+	}
+	if env["JAEGER_AGENT_HOST"] != "jaeger-agent.klstr-system.svc" {
+		t.Errorf("unexpected agent host %q", env["JAEGER_AGENT_HOST"])
+	}
+	if env["JAEGER_SERVICE_NAME"] != mu.Name {
+		t.Errorf("unexpected service name %q", env["JAEGER_SERVICE_NAME"])
+	}
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"fmt"
+
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	jaegerAgentService     = "jaeger-agent"
+	jaegerCollectorService = "jaeger-collector"
+	jaegerAgentPort        = 6831
+	jaegerCollectorPort    = 14268
+)
+
+// tracingEnv points the jaeger clients of a Muservice at the jaeger
+// installed in the klstr namespace. Nothing is set until jaeger is
+// installed.
+func (c *Controller) tracingEnv(mu *klstrv1.Muservice) ([]corev1.EnvVar, error) {
+	_, err := c.servicesLister.Services(c.namespace).Get(jaegerCollectorService)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jaegerEnvVars(mu, c.namespace), nil
+}
+
+// jaegerEnvVars leaves out the variables declared on the Muservice, so
+// that services can point at another collector.
+func jaegerEnvVars(mu *klstrv1.Muservice, namespace string) []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{Name: "JAEGER_SERVICE_NAME", Value: mu.Name},
+		{Name: "JAEGER_AGENT_HOST", Value: fmt.Sprintf("%s.%s.svc", jaegerAgentService, namespace)},
+		{Name: "JAEGER_AGENT_PORT", Value: fmt.Sprintf("%d", jaegerAgentPort)},
+		{Name: "JAEGER_ENDPOINT", Value: fmt.Sprintf("http://%s.%s.svc:%d/api/traces", jaegerCollectorService, namespace, jaegerCollectorPort)},
+	}
+	declared := map[string]bool{}
+	for _, e := range mu.Spec.Environment {
+		declared[e.Name] = true
+	}
+	var tracing []corev1.EnvVar
+	for _, e := range env {
+		if !declared[e.Name] {
+			tracing = append(tracing, e)
+		}
+	}
+	return tracing
+}
@@ -1,24 +1,65 @@
 package klstr
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/klstr/klstr/pkg/command_jobs"
 	"github.com/klstr/klstr/pkg/util"
 	log "github.com/sirupsen/logrus"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	// DatabaseCommandLabel marks the jobs run by klstr database with the
+	// command that created them.
+	DatabaseCommandLabel = "io.klstr/database-command"
+	// databaseJobRetention is how long finished database jobs are kept
+	// before the next klstr database command removes them.
+	databaseJobRetention      = 24 * time.Hour
+	defaultDatabaseJobTimeout = 5 * time.Minute
+)
+
 type DatabaseConfig struct {
 	DBName   string
 	ToDBName string
 	DBType   string
 	DBIName  string
+	// Namespace is the klstr namespace holding the db instances, where
+	// the database jobs run.
+	Namespace string
+	// Timeout bounds how long the job may run.
+	Timeout time.Duration
+	// KeepJob keeps the job after it succeeded. Failed jobs are always
+	// kept for inspection until they are pruned.
+	KeepJob bool
+	// WithUser creates a user owning the database, whose credentials are
+	// written to the UserSecret secret in TargetNamespace.
+	WithUser        bool
+	Username        string
+	UserSecret      string
+	TargetNamespace string
+	// RetentionDays prunes the backups of the database older than it
+	// after each backup, when set.
+	RetentionDays int
 }
 
 type DatabaseJob struct {
-	cs *kubernetes.Clientset
+	cs kubernetes.Interface
 	dc *DatabaseConfig
 }
 
@@ -55,48 +96,345 @@ func CloneDB(dc *DatabaseConfig, kubeconfig string) error {
 }
 
 func (dj *DatabaseJob) CreateDBJob() error {
-	ji := dj.cs.BatchV1().Jobs("klstr")
-	jobobj, err := getJobFromFile()
+	if dj.dc.WithUser {
+		return dj.CreateDBWithUserJob()
+	}
+	jobobj, err := command_jobs.NewJobFromTemplate()
+	if err != nil {
+		return err
+	}
 	err = buildCreateJobCommand(jobobj, dj.dc)
 	if err != nil {
 		return err
 	}
-	job, err := ji.Create(jobobj)
+	_, err = dj.runJob(jobobj, "create", os.Stdout)
+	return err
+}
+
+// CreateDBWithUserJob creates the database along with a user owning it.
+// The credentials secret is written before the job runs so that an
+// existing secret is never overwritten, and removed again when the job
+// fails. The job reads the password from a copy in the klstr namespace,
+// removed once the job finished.
+func (dj *DatabaseJob) CreateDBWithUserJob() error {
+	dbType, ok := util.DatabaseTypes[dj.dc.DBType]
+	if !ok {
+		return fmt.Errorf("invalid database type %s", dj.dc.DBType)
+	}
+	dj.dc.DBType = dbType.CommandJob
+	dbiSecretName := fmt.Sprintf("dbi-%s-%s", dj.dc.DBType, dj.dc.DBIName)
+	dbiSecret, err := dj.cs.CoreV1().Secrets(dj.dc.Namespace).Get(dbiSecretName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return fmt.Errorf("db instance %s of type %s is not registered", dj.dc.DBIName, dj.dc.DBType)
+	}
+	if err != nil {
+		return err
+	}
+	username := dj.dc.Username
+	if username == "" {
+		username = databaseUsername(dj.dc.DBName)
+	}
+	password, err := util.GeneratePassword(24)
 	if err != nil {
-		log.Errorf("unable to create db create job %v", err)
 		return err
 	}
-	log.Infof("Created db create job %+v", job)
+	secretName := dj.dc.UserSecret
+	if secretName == "" {
+		secretName = fmt.Sprintf("%s-db", dj.dc.DBName)
+	}
+	host := string(dbiSecret.Data["host"])
+	port := string(dbiSecret.Data["port"])
+	labels := map[string]string{
+		util.DatabaseLabel:   dj.dc.DBName,
+		util.DBInstanceLabel: dj.dc.DBIName,
+	}
+	si := dj.cs.CoreV1().Secrets(dj.dc.TargetNamespace)
+	secret, err := si.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   secretName,
+			Labels: labels,
+		},
+		StringData: map[string]string{
+			"uri":      fmt.Sprintf("%s://%s:%s@%s:%s/%s", dbType.Scheme, username, password, host, port, dj.dc.DBName),
+			"host":     host,
+			"port":     port,
+			"database": dj.dc.DBName,
+			"user":     username,
+			"password": password,
+		},
+	})
+	if kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("secret %s/%s already exists, pick another with --secret-name", dj.dc.TargetNamespace, secretName)
+	}
+	if err != nil {
+		return err
+	}
+	err = dj.runCreateWithUserJob(username, password, labels)
+	if err != nil {
+		deleteErr := si.Delete(secret.Name, &metav1.DeleteOptions{})
+		if deleteErr != nil {
+			log.Warnf("unable to remove secret %s/%s %v", secret.Namespace, secret.Name, deleteErr)
+		}
+		return err
+	}
+	fmt.Printf("credentials of user %s written to secret %s/%s\n", username, secret.Namespace, secret.Name)
 	return nil
 }
 
+// runCreateWithUserJob runs the job creating the database and its user,
+// with the password in a secret of the klstr namespace for the duration
+// of the job.
+func (dj *DatabaseJob) runCreateWithUserJob(username, password string, labels map[string]string) error {
+	si := dj.cs.CoreV1().Secrets(dj.dc.Namespace)
+	passwordSecret, err := si.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "dbjob-password-",
+			Labels:       labels,
+		},
+		StringData: map[string]string{"password": password},
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := si.Delete(passwordSecret.Name, &metav1.DeleteOptions{})
+		if err != nil {
+			log.Warnf("unable to remove secret %s/%s %v", passwordSecret.Namespace, passwordSecret.Name, err)
+		}
+	}()
+	jobobj, err := command_jobs.NewJobFromTemplate()
+	if err != nil {
+		return err
+	}
+	err = buildCreateWithUserJobCommand(jobobj, dj.dc, username, passwordSecret.Name)
+	if err != nil {
+		return err
+	}
+	_, err = dj.runJob(jobobj, "create", os.Stdout)
+	return err
+}
+
 func (dj *DatabaseJob) CreateCloneDBJob() error {
-	ji := dj.cs.BatchV1().Jobs("klstr")
-	jobobj, err := getJobFromFile()
-	err = buildCloneJobCommand(jobobj, dj.dc)
+	jobobj, err := command_jobs.NewJobFromTemplate()
 	if err != nil {
 		return err
 	}
-	job, err := ji.Create(jobobj)
+	err = buildCloneJobCommand(jobobj, dj.dc)
 	if err != nil {
-		log.Errorf("unable to create db clone job %v", err)
 		return err
 	}
-	log.Infof("Created db clone job %+v", job)
-	return nil
+	_, err = dj.runJob(jobobj, "clone", os.Stdout)
+	return err
 }
 
-func getJobFromFile() (*batchv1.Job, error) {
-	data, err := ioutil.ReadFile("k8s/jobs/dbjob.yaml")
+// runJob runs job to completion, streaming the logs of its pod to out, and
+// returns the logs. The job is not retried as the database commands are
+// not idempotent.
+func (dj *DatabaseJob) runJob(job *batchv1.Job, command string, out io.Writer) (string, error) {
+	ji := dj.cs.BatchV1().Jobs(dj.dc.Namespace)
+	job, deadline, err := dj.createJob(job, command)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	schemaDecoder := util.NewSchemaDecoder(data)
-	object, err := schemaDecoder.Decode()
+	fmt.Fprintf(out, "job %s/%s created\n", job.Namespace, job.Name)
+	logs := &bytes.Buffer{}
+	pod, err := dj.waitForJobPod(job, time.Until(deadline))
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	if pod != nil {
+		err = dj.streamLogs(pod, io.MultiWriter(out, logs))
+		if err != nil {
+			log.Warnf("unable to stream the logs of pod %s %v", pod.Name, err)
+		}
+	}
+	job, err = dj.waitForJob(job, time.Until(deadline))
+	if err != nil {
+		return "", err
+	}
+	if failed, message := jobFailed(job); failed {
+		fmt.Printf("job %s/%s kept for inspection\n", job.Namespace, job.Name)
+		return "", fmt.Errorf("database %s failed: %s", command, jobError(logs.String(), message))
+	}
+	if !dj.dc.KeepJob {
+		err = ji.Delete(job.Name, backgroundDeletion())
+	}
+	return logs.String(), err
+}
+
+// createJob creates the job of command, which is not retried and has to
+// finish within the timeout of the database config, by the returned
+// deadline. The jobs of earlier commands that expired are removed first.
+func (dj *DatabaseJob) createJob(job *batchv1.Job, command string) (*batchv1.Job, time.Time, error) {
+	timeout := dj.dc.Timeout
+	if timeout == 0 {
+		timeout = defaultDatabaseJobTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	err := dj.pruneJobs()
+	if err != nil {
+		log.Warnf("unable to remove finished database jobs %v", err)
+	}
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64(timeout / time.Second)
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.ActiveDeadlineSeconds = &activeDeadlineSeconds
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[DatabaseCommandLabel] = command
+	job, err = dj.cs.BatchV1().Jobs(dj.dc.Namespace).Create(job)
+	if err != nil {
+		log.Errorf("unable to create db %s job %v", command, err)
+		return nil, deadline, err
+	}
+	return job, deadline, nil
+}
+
+// waitForJobPod waits for the pod of job to start. It returns no pod when
+// the job finished before a pod was seen, and fails early on pods that can
+// never start, such as pods referencing a missing db instance secret.
+func (dj *DatabaseJob) waitForJobPod(job *batchv1.Job, timeout time.Duration) (*corev1.Pod, error) {
+	pi := dj.cs.CoreV1().Pods(job.Namespace)
+	ji := dj.cs.BatchV1().Jobs(job.Namespace)
+	var pod *corev1.Pod
+	err := wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		pods, err := pi.List(metav1.ListOptions{LabelSelector: "job-name=" + job.Name})
+		if err != nil {
+			return false, err
+		}
+		for i := range pods.Items {
+			p := &pods.Items[i]
+			if p.Status.Phase != corev1.PodPending {
+				pod = p
+				return true, nil
+			}
+			for _, cs := range p.Status.ContainerStatuses {
+				waiting := cs.State.Waiting
+				if waiting == nil {
+					continue
+				}
+				switch waiting.Reason {
+				case "CreateContainerConfigError", "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+					return false, fmt.Errorf("pod %s of job %s cannot start: %s", p.Name, job.Name, waiting.Message)
+				}
+			}
+		}
+		current, err := ji.Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return jobFinished(current), nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("timed out waiting for job %s/%s to start", job.Namespace, job.Name)
+	}
+	return pod, err
+}
+
+func (dj *DatabaseJob) streamLogs(pod *corev1.Pod, w io.Writer) error {
+	req := dj.cs.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Follow: true})
+	stream, err := req.Stream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+func (dj *DatabaseJob) waitForJob(job *batchv1.Job, timeout time.Duration) (*batchv1.Job, error) {
+	ji := dj.cs.BatchV1().Jobs(job.Namespace)
+	err := wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		current, err := ji.Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		job = current
+		return jobFinished(job), nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("timed out waiting for job %s/%s to finish", job.Namespace, job.Name)
+	}
+	return job, err
+}
+
+// pruneJobs removes the jobs of earlier klstr database commands that
+// finished more than databaseJobRetention ago.
+func (dj *DatabaseJob) pruneJobs() error {
+	ji := dj.cs.BatchV1().Jobs(dj.dc.Namespace)
+	jobs, err := ji.List(metav1.ListOptions{LabelSelector: DatabaseCommandLabel})
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs.Items {
+		finished := jobFinishedAt(&job)
+		if finished == nil || time.Since(finished.Time) < databaseJobRetention {
+			continue
+		}
+		log.Infof("Removing finished database job %s", job.Name)
+		err = ji.Delete(job.Name, backgroundDeletion())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func backgroundDeletion() *metav1.DeleteOptions {
+	propagation := metav1.DeletePropagationBackground
+	return &metav1.DeleteOptions{PropagationPolicy: &propagation}
+}
+
+func jobFinished(job *batchv1.Job) bool {
+	return jobFinishedAt(job) != nil
+}
+
+// jobFinishedAt returns when job completed or failed, or nil while it runs.
+func jobFinishedAt(job *batchv1.Job) *metav1.Time {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed {
+			return &condition.LastTransitionTime
+		}
+	}
+	return nil
+}
+
+func jobFailed(job *batchv1.Job) (bool, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true, condition.Message
+		}
+	}
+	return false, ""
+}
+
+// jobError picks the sql errors out of the logs of a failed job, falling
+// back to the last line logged and then to the reason the job failed.
+func jobError(logs string, message string) string {
+	var sqlErrors, lines []string
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		if strings.Contains(line, "ERROR") {
+			sqlErrors = append(sqlErrors, line)
+		}
+	}
+	switch {
+	case len(sqlErrors) > 0:
+		return strings.Join(sqlErrors, "; ")
+	case len(lines) > 0:
+		return lines[len(lines)-1]
+	case message != "":
+		return message
 	}
-	return object.(*batchv1.Job), nil
+	return "unknown error"
 }
 
 func buildCloneJobCommand(object *batchv1.Job, dc *DatabaseConfig) error {
@@ -113,6 +451,21 @@ func buildCloneJobCommand(object *batchv1.Job, dc *DatabaseConfig) error {
 	return nil
 }
 
+func buildCreateWithUserJobCommand(object *batchv1.Job, dc *DatabaseConfig, username, passwordSecret string) error {
+	cj, err := command_jobs.CreateCommandJob(dc.DBType, command_jobs.CommandJobOptions{
+		DBName:         dc.DBName,
+		DBIName:        dc.DBIName,
+		Username:       username,
+		PasswordSecret: passwordSecret,
+	})
+	if err != nil {
+		log.Errorf("unable to create command job %v", err)
+		return err
+	}
+	cj.BuildCreateWithUserCommand(object)
+	return nil
+}
+
 func buildCreateJobCommand(object *batchv1.Job, dc *DatabaseConfig) error {
 	cj, err := command_jobs.CreateCommandJob(dc.DBType, command_jobs.CommandJobOptions{
 		DBName:   dc.DBName,
@@ -126,3 +479,171 @@ func buildCreateJobCommand(object *batchv1.Job, dc *DatabaseConfig) error {
 	cj.BuildCreateCommand(object)
 	return nil
 }
+
+// databaseUsername derives the user of a database from its name, within
+// the 32 character limit on mysql user names.
+func databaseUsername(dbName string) string {
+	user := strings.ToLower(strings.Replace(dbName, "-", "_", -1))
+	if len(user) > 32 {
+		user = user[:32]
+	}
+	return user
+}
+
+// protectedDatabases are the databases of the instances themselves, which
+// klstr database drop refuses to drop.
+var protectedDatabases = map[string]bool{
+	"postgres":           true,
+	"template0":          true,
+	"template1":          true,
+	"mysql":              true,
+	"information_schema": true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+// DatabaseInfo is a database of a db instance.
+type DatabaseInfo struct {
+	Name string
+	// Owner is the owning role of a postgres database, and the users
+	// granted privileges on a mysql database.
+	Owner string
+	// Size is the size of the database in bytes.
+	Size int64
+}
+
+func ListDBs(dc *DatabaseConfig, kubeconfig string) ([]DatabaseInfo, error) {
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	dj := DatabaseJob{
+		cs: cs,
+		dc: dc,
+	}
+	return dj.ListDBJob()
+}
+
+func DropDB(dc *DatabaseConfig, kubeconfig string) error {
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	dj := DatabaseJob{
+		cs: cs,
+		dc: dc,
+	}
+	return dj.DropDBJob()
+}
+
+// ListDBJob queries the databases of the instance with a job, whose
+// output is parsed instead of printed.
+func (dj *DatabaseJob) ListDBJob() ([]DatabaseInfo, error) {
+	jobobj, err := command_jobs.NewJobFromTemplate()
+	if err != nil {
+		return nil, err
+	}
+	cj, err := command_jobs.CreateCommandJob(dj.dc.DBType, command_jobs.CommandJobOptions{
+		DBIName: dj.dc.DBIName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cj.BuildListCommand(jobobj)
+	logs, err := dj.runJob(jobobj, "list", ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	return parseDatabaseList(logs), nil
+}
+
+func (dj *DatabaseJob) DropDBJob() error {
+	if dj.dc.DBName == "" {
+		return errors.New("no database given")
+	}
+	if protectedDatabases[dj.dc.DBName] {
+		return fmt.Errorf("refusing to drop the %s system database", dj.dc.DBName)
+	}
+	jobobj, err := command_jobs.NewJobFromTemplate()
+	if err != nil {
+		return err
+	}
+	cj, err := command_jobs.CreateCommandJob(dj.dc.DBType, command_jobs.CommandJobOptions{
+		DBName:  dj.dc.DBName,
+		DBIName: dj.dc.DBIName,
+	})
+	if err != nil {
+		return err
+	}
+	cj.BuildDropCommand(jobobj)
+	_, err = dj.runJob(jobobj, "drop", os.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("database %s dropped\n", dj.dc.DBName)
+	return nil
+}
+
+// DatabaseReferences returns the muservices declaring the database and the
+// secrets holding credentials for it, in every namespace.
+func DatabaseReferences(dc *DatabaseConfig, kubeconfig string) ([]string, error) {
+	cs, err := util.NewKubeClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	kcs, err := util.NewKlstrClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	var references []string
+	mus, err := kcs.KlstrV1().Muservices(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil && !kerrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+	if err == nil {
+		for _, mu := range mus.Items {
+			for _, db := range mu.Spec.Databases {
+				dbType, ok := util.DatabaseTypes[db.Type]
+				if ok && db.Name == dc.DBName && db.Instance == dc.DBIName && dbType.CommandJob == dc.DBType {
+					references = append(references, fmt.Sprintf("muservice %s/%s", mu.Namespace, mu.Name))
+				}
+			}
+		}
+	}
+	// names that are not label values cannot be on credentials secrets
+	if len(validation.IsValidLabelValue(dc.DBName)) > 0 || len(validation.IsValidLabelValue(dc.DBIName)) > 0 {
+		return references, nil
+	}
+	selector := fmt.Sprintf("%s=%s,%s=%s", util.DatabaseLabel, dc.DBName, util.DBInstanceLabel, dc.DBIName)
+	secrets, err := cs.CoreV1().Secrets(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets.Items {
+		references = append(references, fmt.Sprintf("secret %s/%s", secret.Namespace, secret.Name))
+	}
+	return references, nil
+}
+
+// parseDatabaseList parses the tab separated output of a list job,
+// skipping the warnings the clients log along with it and the system
+// databases of the instance.
+func parseDatabaseList(logs string) []DatabaseInfo {
+	var databases []DatabaseInfo
+	for _, line := range strings.Split(logs, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) != 3 || protectedDatabases[fields[0]] {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		databases = append(databases, DatabaseInfo{
+			Name:  fields[0],
+			Owner: fields[1],
+			Size:  size,
+		})
+	}
+	return databases
+}
@@ -0,0 +1,204 @@
+package klstr
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "klstr-system"
+
+func newTestDatabaseJob(objects ...runtime.Object) (*DatabaseJob, *fake.Clientset) {
+	cs := fake.NewSimpleClientset(objects...)
+	return &DatabaseJob{
+		cs: cs,
+		dc: &DatabaseConfig{Namespace: testNamespace, Timeout: time.Minute},
+	}, cs
+}
+
+func jobCondition(conditionType batchv1.JobConditionType, reason, message string, at time.Time) batchv1.JobCondition {
+	return batchv1.JobCondition{
+		Type:               conditionType,
+		Status:             corev1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.NewTime(at),
+	}
+}
+
+// finishJobsOnCreate has the jobs created through cs finish right away
+// with condition, as there is no job controller behind the fake.
+func finishJobsOnCreate(cs *fake.Clientset, condition batchv1.JobCondition, created *batchv1.Job) {
+	cs.PrependReactor("create", "jobs", func(action ktesting.Action) (bool, runtime.Object, error) {
+		job := action.(ktesting.CreateAction).GetObject().(*batchv1.Job)
+		job.Status.Conditions = []batchv1.JobCondition{condition}
+		*created = *job
+		return false, nil, nil
+	})
+}
+
+func newTestJob() *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "dbjob-create-1"}}
+}
+
+func TestRunJobSucceeds(t *testing.T) {
+	dj, cs := newTestDatabaseJob()
+	var created batchv1.Job
+	finishJobsOnCreate(cs, jobCondition(batchv1.JobComplete, "", "", time.Now()), &created)
+	_, err := dj.runJob(newTestJob(), "create", ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Spec.BackoffLimit == nil || *created.Spec.BackoffLimit != 0 {
+		t.Errorf("expected the job not to be retried, got backoff limit %v", created.Spec.BackoffLimit)
+	}
+	if created.Spec.ActiveDeadlineSeconds == nil || *created.Spec.ActiveDeadlineSeconds != 60 {
+		t.Errorf("expected the job to be bounded by the timeout, got deadline %v", created.Spec.ActiveDeadlineSeconds)
+	}
+	if created.Labels[DatabaseCommandLabel] != "create" {
+		t.Errorf("expected the job to be labelled with its command, got labels %v", created.Labels)
+	}
+	jobs, err := cs.BatchV1().Jobs(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("expected the succeeded job to be removed, got %d jobs", len(jobs.Items))
+	}
+}
+
+func TestRunJobFails(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition batchv1.JobCondition
+	}{
+		{
+			"backoff limit exceeded",
+			jobCondition(batchv1.JobFailed, "BackoffLimitExceeded", "Job has reached the specified backoff limit", time.Now()),
+		},
+		{
+			"deadline exceeded",
+			jobCondition(batchv1.JobFailed, "DeadlineExceeded", "Job was active longer than specified deadline", time.Now()),
+		},
+	}
+	for _, test := range tests {
+		dj, cs := newTestDatabaseJob()
+		var created batchv1.Job
+		finishJobsOnCreate(cs, test.condition, &created)
+		_, err := dj.runJob(newTestJob(), "create", ioutil.Discard)
+		if err == nil {
+			t.Errorf("%s: expected the job to fail", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.condition.Message) {
+			t.Errorf("%s: expected the error to carry %q, got %v", test.name, test.condition.Message, err)
+		}
+		_, err = cs.BatchV1().Jobs(testNamespace).Get(created.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("%s: expected the failed job to be kept, got %v", test.name, err)
+		}
+	}
+}
+
+func TestPruneJobs(t *testing.T) {
+	job := func(name string, labelled bool, conditions ...batchv1.JobCondition) runtime.Object {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+			Status:     batchv1.JobStatus{Conditions: conditions},
+		}
+		if labelled {
+			job.Labels = map[string]string{DatabaseCommandLabel: "create"}
+		}
+		return job
+	}
+	expired := time.Now().Add(-databaseJobRetention - time.Hour)
+	dj, cs := newTestDatabaseJob(
+		job("expired", true, jobCondition(batchv1.JobComplete, "", "", expired)),
+		job("expired-failed", true, jobCondition(batchv1.JobFailed, "DeadlineExceeded", "", expired)),
+		job("recent", true, jobCondition(batchv1.JobComplete, "", "", time.Now())),
+		job("running", true),
+		job("unlabelled", false, jobCondition(batchv1.JobComplete, "", "", expired)),
+	)
+	err := dj.pruneJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := cs.BatchV1().Jobs(testNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, job := range jobs.Items {
+		kept = append(kept, job.Name)
+	}
+	sort.Strings(kept)
+	expected := "recent,running,unlabelled"
+	if strings.Join(kept, ",") != expected {
+		t.Errorf("expected jobs %s to be kept, got %s", expected, strings.Join(kept, ","))
+	}
+}
+
+func TestJobError(t *testing.T) {
+	tests := []struct {
+		logs     string
+		message  string
+		expected string
+	}{
+		{"psql:<stdin>:3: ERROR:  role \"a\" already exists\n", "failed", "psql:<stdin>:3: ERROR:  role \"a\" already exists"},
+		{"creating\nconnection refused\n", "failed", "connection refused"},
+		{"", "Job was active longer than specified deadline", "Job was active longer than specified deadline"},
+		{"", "", "unknown error"},
+	}
+	for _, test := range tests {
+		got := jobError(test.logs, test.message)
+		if got != test.expected {
+			t.Errorf("expected error %q for logs %q, got %q", test.expected, test.logs, got)
+		}
+	}
+}
+
+func TestParseDatabaseList(t *testing.T) {
+	tests := []struct {
+		name     string
+		logs     string
+		expected []DatabaseInfo
+	}{
+		{"empty output", "", nil},
+		{
+			"pg databases",
+			"orders\tapp\t7729695\nusers\tapp\t8012345\n",
+			[]DatabaseInfo{{Name: "orders", Owner: "app", Size: 7729695}, {Name: "users", Owner: "app", Size: 8012345}},
+		},
+		{
+			"system databases",
+			"postgres\tpostgres\t7729695\nmysql\t\t0\nsys\t\t0\norders\tapp\t42\n",
+			[]DatabaseInfo{{Name: "orders", Owner: "app", Size: 42}},
+		},
+		{
+			"mysql databases without grants",
+			"orders\t\t16384\r\n",
+			[]DatabaseInfo{{Name: "orders", Owner: "", Size: 16384}},
+		},
+		{
+			"malformed lines",
+			"mysql: [Warning] Using a password on the command line interface can be insecure.\norders\tapp\nusers\tapp\tlarge\nitems\tapp\t1\textra\ncarts\tapp\t10\n",
+			[]DatabaseInfo{{Name: "carts", Owner: "app", Size: 10}},
+		},
+	}
+	for _, test := range tests {
+		got := parseDatabaseList(test.logs)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, got)
+		}
+	}
+}
@@ -17,6 +17,8 @@ type DBInstanceRegistration struct {
 	Port     int
 	Username string
 	Password string
+	// Namespace is the klstr namespace the registration is stored in.
+	Namespace string
 }
 
 func RegisterDBInstance(dbr *DBInstanceRegistration, kubeconfig string) error {
@@ -24,21 +26,16 @@ func RegisterDBInstance(dbr *DBInstanceRegistration, kubeconfig string) error {
 	if err != nil {
 		return err
 	}
-	ns, err := cs.CoreV1().Namespaces().Get("klstr", metav1.GetOptions{})
-	log.Info(ns)
-	log.Info(fmt.Sprintf("%+v", err))
+	err = util.EnsureNamespace(cs, dbr.Namespace)
 	if err != nil {
-		createdNs, err := cs.CoreV1().Namespaces().Create(&corev1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{Name: "klstr"},
-		})
-		if err != nil {
-			return err
-		}
-		log.Infof("created namespace %v", createdNs)
+		return err
 	}
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: fmt.Sprintf("dbi-%s-%s", dbr.DBType, dbr.Name),
+			Labels: map[string]string{
+				util.DBInstanceLabel: dbr.Name,
+			},
 		},
 		StringData: map[string]string{
 			"dbtype":   "postgres",
@@ -48,7 +45,7 @@ func RegisterDBInstance(dbr *DBInstanceRegistration, kubeconfig string) error {
 			"password": dbr.Password,
 		},
 	}
-	createdSec, err := cs.CoreV1().Secrets("klstr").Create(secret)
+	createdSec, err := cs.CoreV1().Secrets(dbr.Namespace).Create(secret)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,189 @@
+package klstr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
+	clientset "github.com/klstr/klstr/pkg/client/clientset/versioned"
+	"github.com/klstr/klstr/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+type DeployOptions struct {
+	KubeConfig string
+	Namespace  string
+	// File is a Muservice manifest to create or update.
+	File string
+	// Name, Image and Replicas patch an existing Muservice when no file
+	// is given.
+	Name     string
+	Image    string
+	Replicas *int32
+	Wait     bool
+	Timeout  time.Duration
+}
+
+type Deployer struct {
+	do  DeployOptions
+	cs  kubernetes.Interface
+	kcs clientset.Interface
+}
+
+func NewDeployer(do DeployOptions) (*Deployer, error) {
+	cs, err := util.NewKubeClient(do.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	kcs, err := util.NewKlstrClient(do.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &Deployer{do: do, cs: cs, kcs: kcs}, nil
+}
+
+func (d *Deployer) Deploy() error {
+	var mu *klstrv1.Muservice
+	var err error
+	if d.do.File != "" {
+		mu, err = d.applyFile()
+	} else {
+		mu, err = d.patch()
+	}
+	if err != nil {
+		return err
+	}
+	if !d.do.Wait {
+		return nil
+	}
+	return d.waitForRollout(mu)
+}
+
+func (d *Deployer) applyFile() (*klstrv1.Muservice, error) {
+	data, err := ioutil.ReadFile(d.do.File)
+	if err != nil {
+		return nil, err
+	}
+	object, err := util.NewSchemaDecoder(data).Decode()
+	if err != nil {
+		return nil, err
+	}
+	desired, ok := object.(*klstrv1.Muservice)
+	if !ok {
+		return nil, fmt.Errorf("%s does not describe a Muservice", d.do.File)
+	}
+	if desired.Namespace == "" {
+		desired.Namespace = d.do.Namespace
+	}
+	mi := d.kcs.KlstrV1().Muservices(desired.Namespace)
+	existing, err := mi.Get(desired.Name, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		created, err := mi.Create(desired)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("muservice %s/%s created\n", created.Namespace, created.Name)
+		return created, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	desired.ResourceVersion = existing.ResourceVersion
+	updated, err := mi.Update(desired)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("muservice %s/%s configured\n", updated.Namespace, updated.Name)
+	return updated, nil
+}
+
+func (d *Deployer) patch() (*klstrv1.Muservice, error) {
+	if d.do.Name == "" {
+		return nil, errors.New("either a muservice file or a muservice name is required")
+	}
+	spec := map[string]interface{}{}
+	if d.do.Image != "" {
+		spec["image"] = d.do.Image
+	}
+	if d.do.Replicas != nil {
+		spec["replicas"] = *d.do.Replicas
+	}
+	if len(spec) == 0 {
+		return nil, errors.New("nothing to deploy, set --image or --scale")
+	}
+	data, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return nil, err
+	}
+	mu, err := d.kcs.KlstrV1().Muservices(d.do.Namespace).Patch(d.do.Name, types.MergePatchType, data)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("muservice %s/%s patched\n", mu.Namespace, mu.Name)
+	return mu, nil
+}
+
+// waitForRollout waits for the controller to observe the Muservice and for
+// its deployment to finish rolling out.
+func (d *Deployer) waitForRollout(mu *klstrv1.Muservice) error {
+	mi := d.kcs.KlstrV1().Muservices(mu.Namespace)
+	di := d.cs.AppsV1().Deployments(mu.Namespace)
+	lastMessage := ""
+	err := wait.PollImmediate(2*time.Second, d.do.Timeout, func() (bool, error) {
+		current, err := mi.Get(mu.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		message := ""
+		done := false
+		deployment, err := di.Get(mu.Name, metav1.GetOptions{})
+		switch {
+		case kerrors.IsNotFound(err):
+			message = "Waiting for deployment to be created"
+		case err != nil:
+			return false, err
+		case current.Status.ObservedGeneration < current.Generation:
+			message = "Waiting for muservice spec to be observed"
+		default:
+			message, done = rolloutStatus(deployment)
+		}
+		if message != lastMessage {
+			fmt.Println(message)
+			lastMessage = message
+		}
+		return done, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for muservice %s/%s to roll out", mu.Namespace, mu.Name)
+	}
+	return err
+}
+
+func rolloutStatus(deployment *appsv1.Deployment) (string, bool) {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return "Waiting for deployment spec update to be observed", false
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	if status.UpdatedReplicas < replicas {
+		return fmt.Sprintf("Waiting for rollout to finish: %d out of %d new replicas have been updated", status.UpdatedReplicas, replicas), false
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		return fmt.Sprintf("Waiting for rollout to finish: %d old replicas are pending termination", status.Replicas-status.UpdatedReplicas), false
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return fmt.Sprintf("Waiting for rollout to finish: %d of %d updated replicas are available", status.AvailableReplicas, status.UpdatedReplicas), false
+	}
+	return fmt.Sprintf("muservice %s successfully rolled out", deployment.Name), true
+}
@@ -0,0 +1,356 @@
+package manifests
+
+import (
+	"errors"
+	"fmt"
+
+	prometheusop "github.com/coreos/prometheus-operator/pkg/client/monitoring"
+	prometheusopv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/ghodss/yaml"
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// AlertmanagerComponent is the component installing alertmanager and the
+// klstr alerting rules.
+const AlertmanagerComponent = "alertmanager"
+
+const (
+	// AlertmanagerConfigSecret is the secret the operator reads the
+	// configuration of the main alertmanager from.
+	AlertmanagerConfigSecret = "alertmanager-main"
+	AlertmanagerConfigKey    = "alertmanager.yaml"
+)
+
+type AlertmanagerInstaller struct {
+	cs        *kubernetes.Clientset
+	ps        *prometheusop.Clientset
+	applier   *Applier
+	namespace string
+}
+
+func NewAlertmanagerInstaller(
+	cs *kubernetes.Clientset,
+	ps *prometheusop.Clientset,
+	applier *Applier,
+	namespace string,
+) *AlertmanagerInstaller {
+	return &AlertmanagerInstaller{
+		cs:        cs,
+		ps:        ps,
+		applier:   applier,
+		namespace: namespace,
+	}
+}
+
+// InstallService applies alertmanager and the alerting rules. The
+// configuration secret is only created when it does not exist, so that
+// receivers set up with klstr alerts configure survive re-adopting.
+func (ai *AlertmanagerInstaller) InstallService() error {
+	objects, err := ai.Objects()
+	if err != nil {
+		return err
+	}
+	ready := map[string]bool{}
+	for _, object := range objects {
+		err = waitForMonitoringCRD(ai.ps, ai.namespace, object, ready)
+		if err != nil {
+			return err
+		}
+		if secret, ok := object.(*corev1.Secret); ok {
+			_, err = ai.cs.CoreV1().Secrets(ai.namespace).Get(secret.Name, metav1.GetOptions{})
+			if err == nil {
+				log.Infof("Keeping the existing alertmanager configuration in secret %s", secret.Name)
+				continue
+			}
+			if !kerrors.IsNotFound(err) {
+				return err
+			}
+		}
+		_, err = ai.applier.Apply(ai.namespace, object)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ai *AlertmanagerInstaller) Objects() ([]runtime.Object, error) {
+	config, err := getAlertmanagerConfigSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	alertmanager, err := getAlertmanagerSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	service, err := getAlertmanagerServiceSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := getPrometheusRulesSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{config, alertmanager, service, rules}, nil
+}
+
+func (ai *AlertmanagerInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "alerting", Component: AlertmanagerComponent}
+	statefulSetStatus(ai.cs, ai.namespace, "alertmanager-main", &status)
+	return status
+}
+
+// AlertmanagerConfig is the part of the alertmanager configuration klstr
+// manages. Every alert is routed to a single receiver.
+type AlertmanagerConfig struct {
+	Global    AlertmanagerGlobal     `json:"global"`
+	Route     AlertmanagerRoute      `json:"route"`
+	Receivers []AlertmanagerReceiver `json:"receivers"`
+}
+
+type AlertmanagerGlobal struct {
+	ResolveTimeout   string `json:"resolve_timeout,omitempty"`
+	SMTPSmarthost    string `json:"smtp_smarthost,omitempty"`
+	SMTPFrom         string `json:"smtp_from,omitempty"`
+	SMTPAuthUsername string `json:"smtp_auth_username,omitempty"`
+	SMTPAuthPassword string `json:"smtp_auth_password,omitempty"`
+}
+
+type AlertmanagerRoute struct {
+	Receiver       string   `json:"receiver"`
+	GroupBy        []string `json:"group_by,omitempty"`
+	GroupWait      string   `json:"group_wait,omitempty"`
+	GroupInterval  string   `json:"group_interval,omitempty"`
+	RepeatInterval string   `json:"repeat_interval,omitempty"`
+}
+
+type AlertmanagerReceiver struct {
+	Name             string            `json:"name"`
+	SlackConfigs     []SlackConfig     `json:"slack_configs,omitempty"`
+	EmailConfigs     []EmailConfig     `json:"email_configs,omitempty"`
+	PagerdutyConfigs []PagerdutyConfig `json:"pagerduty_configs,omitempty"`
+}
+
+type SlackConfig struct {
+	APIURL       string `json:"api_url"`
+	Channel      string `json:"channel,omitempty"`
+	SendResolved bool   `json:"send_resolved"`
+}
+
+type EmailConfig struct {
+	To           string `json:"to"`
+	SendResolved bool   `json:"send_resolved"`
+}
+
+type PagerdutyConfig struct {
+	ServiceKey string `json:"service_key"`
+}
+
+// NewAlertmanagerConfig returns the klstr routing sending every alert to
+// receiver.
+func NewAlertmanagerConfig(receiver AlertmanagerReceiver) AlertmanagerConfig {
+	return AlertmanagerConfig{
+		Global: AlertmanagerGlobal{ResolveTimeout: "5m"},
+		Route: AlertmanagerRoute{
+			Receiver:       receiver.Name,
+			GroupBy:        []string{"alertname", "namespace"},
+			GroupWait:      "30s",
+			GroupInterval:  "5m",
+			RepeatInterval: "4h",
+		},
+		Receivers: []AlertmanagerReceiver{receiver},
+	}
+}
+
+func (ac AlertmanagerConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(ac)
+}
+
+// alertmanagerConfigFile is the part of a complete alertmanager
+// configuration checked before it is applied.
+type alertmanagerConfigFile struct {
+	Global struct {
+		SMTPSmarthost string `json:"smtp_smarthost"`
+		SMTPFrom      string `json:"smtp_from"`
+		SlackAPIURL   string `json:"slack_api_url"`
+	} `json:"global"`
+	Route     *alertmanagerRouteFile `json:"route"`
+	Receivers []struct {
+		Name         string `json:"name"`
+		SlackConfigs []struct {
+			APIURL string `json:"api_url"`
+		} `json:"slack_configs"`
+		EmailConfigs []struct {
+			To        string `json:"to"`
+			Smarthost string `json:"smarthost"`
+			From      string `json:"from"`
+		} `json:"email_configs"`
+		PagerdutyConfigs []struct {
+			ServiceKey string `json:"service_key"`
+			RoutingKey string `json:"routing_key"`
+		} `json:"pagerduty_configs"`
+	} `json:"receivers"`
+}
+
+type alertmanagerRouteFile struct {
+	Receiver string                  `json:"receiver"`
+	Routes   []alertmanagerRouteFile `json:"routes"`
+}
+
+// alertmanagerConfigKeys are the top level keys of an alertmanager
+// configuration. alertmanager refuses to load configurations with others.
+var alertmanagerConfigKeys = map[string]bool{
+	"global":        true,
+	"route":         true,
+	"inhibit_rules": true,
+	"receivers":     true,
+	"templates":     true,
+}
+
+// ValidateAlertmanagerConfig checks config the way alertmanager does when
+// loading it, so that a broken configuration is not handed to it.
+func ValidateAlertmanagerConfig(config []byte) error {
+	var keys map[string]interface{}
+	err := yaml.Unmarshal(config, &keys)
+	if err != nil {
+		return fmt.Errorf("invalid alertmanager configuration: %s", err)
+	}
+	for key := range keys {
+		if !alertmanagerConfigKeys[key] {
+			return fmt.Errorf("invalid alertmanager configuration: unknown field %s", key)
+		}
+	}
+	var ac alertmanagerConfigFile
+	err = yaml.Unmarshal(config, &ac)
+	if err != nil {
+		return fmt.Errorf("invalid alertmanager configuration: %s", err)
+	}
+	receivers := map[string]bool{}
+	for _, receiver := range ac.Receivers {
+		if receiver.Name == "" {
+			return errors.New("invalid alertmanager configuration: missing name in receiver")
+		}
+		if receivers[receiver.Name] {
+			return fmt.Errorf("invalid alertmanager configuration: receiver %s is not unique", receiver.Name)
+		}
+		receivers[receiver.Name] = true
+		for _, slack := range receiver.SlackConfigs {
+			if slack.APIURL == "" && ac.Global.SlackAPIURL == "" {
+				return fmt.Errorf("invalid alertmanager configuration: receiver %s has no slack api url and no global one is set", receiver.Name)
+			}
+		}
+		for _, email := range receiver.EmailConfigs {
+			if email.To == "" {
+				return fmt.Errorf("invalid alertmanager configuration: receiver %s has an email config without a to address", receiver.Name)
+			}
+			if email.Smarthost == "" && ac.Global.SMTPSmarthost == "" || email.From == "" && ac.Global.SMTPFrom == "" {
+				return fmt.Errorf("invalid alertmanager configuration: receiver %s has no smtp smarthost or from address and no global one is set", receiver.Name)
+			}
+		}
+		for _, pagerduty := range receiver.PagerdutyConfigs {
+			if pagerduty.ServiceKey == "" && pagerduty.RoutingKey == "" {
+				return fmt.Errorf("invalid alertmanager configuration: receiver %s has a pagerduty config without a service or routing key", receiver.Name)
+			}
+		}
+	}
+	if ac.Route == nil || ac.Route.Receiver == "" {
+		return errors.New("invalid alertmanager configuration: the route has no default receiver")
+	}
+	return checkRouteReceivers(*ac.Route, receivers)
+}
+
+func checkRouteReceivers(route alertmanagerRouteFile, receivers map[string]bool) error {
+	if route.Receiver != "" && !receivers[route.Receiver] {
+		return fmt.Errorf("invalid alertmanager configuration: undefined receiver %s used in route", route.Receiver)
+	}
+	for _, child := range route.Routes {
+		err := checkRouteReceivers(child, receivers)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AlertmanagerConfigSecretFor returns the alertmanager configuration
+// secret holding config.
+func AlertmanagerConfigSecretFor(config []byte) (*corev1.Secret, error) {
+	if len(config) == 0 {
+		return nil, errors.New("alertmanager configuration is empty")
+	}
+	secret, err := getAlertmanagerConfigSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	secret.Data = map[string][]byte{AlertmanagerConfigKey: config}
+	return secret, nil
+}
+
+// getAlertmanagerConfigSpecFromFile moves the string data of the secret
+// to its data, which is what the api server returns.
+func getAlertmanagerConfigSpecFromFile() (*corev1.Secret, error) {
+	data, err := assets.ReadFile("monitoring/alertmanager-config.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	secret := object.(*corev1.Secret)
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for key, value := range secret.StringData {
+		secret.Data[key] = []byte(value)
+	}
+	secret.StringData = nil
+	return secret, nil
+}
+
+func getAlertmanagerSpecFromFile() (*prometheusopv1.Alertmanager, error) {
+	data, err := assets.ReadFile("monitoring/alertmanager.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode(&prometheusopv1.Alertmanager{})
+	if err != nil {
+		return nil, err
+	}
+	return object.(*prometheusopv1.Alertmanager), nil
+}
+
+func getAlertmanagerServiceSpecFromFile() (*corev1.Service, error) {
+	data, err := assets.ReadFile("monitoring/alertmanager-service.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return object.(*corev1.Service), nil
+}
+
+func getPrometheusRulesSpecFromFile() (*prometheusopv1.PrometheusRule, error) {
+	data, err := assets.ReadFile("monitoring/prometheus-rules.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode(&prometheusopv1.PrometheusRule{})
+	if err != nil {
+		return nil, err
+	}
+	return object.(*prometheusopv1.PrometheusRule), nil
+}
@@ -0,0 +1,78 @@
+package manifests
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAlertmanagerConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "routed receivers",
+			config: `
+global:
+  smtp_smarthost: smtp.example.com:587
+  smtp_from: klstr@example.com
+route:
+  receiver: oncall
+  routes:
+  - receiver: team
+    match:
+      namespace: orders
+receivers:
+- name: oncall
+  pagerduty_configs:
+  - service_key: key
+- name: team
+  email_configs:
+  - to: team@example.com
+`,
+		},
+		{name: "not yaml", config: "route: [", err: "invalid alertmanager configuration"},
+		{name: "unknown field", config: "route:\n  receiver: a\nreceivers:\n- name: a\nreciever: a\n", err: "unknown field reciever"},
+		{name: "no route", config: "receivers:\n- name: a\n", err: "no default receiver"},
+		{name: "undefined receiver", config: "route:\n  receiver: a\n  routes:\n  - receiver: b\nreceivers:\n- name: a\n", err: "undefined receiver b"},
+		{name: "duplicate receiver", config: "route:\n  receiver: a\nreceivers:\n- name: a\n- name: a\n", err: "receiver a is not unique"},
+		{name: "unnamed receiver", config: "route:\n  receiver: a\nreceivers:\n- slack_configs: []\n", err: "missing name"},
+		{
+			name:   "email without smarthost",
+			config: "route:\n  receiver: a\nreceivers:\n- name: a\n  email_configs:\n  - to: oncall@example.com\n",
+			err:    "no smtp smarthost",
+		},
+		{
+			name:   "slack without api url",
+			config: "route:\n  receiver: a\nreceivers:\n- name: a\n  slack_configs:\n  - channel: '#alerts'\n",
+			err:    "no slack api url",
+		},
+	}
+	for _, test := range tests {
+		err := ValidateAlertmanagerConfig([]byte(test.config))
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%s: expected the config to be valid, got %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestValidateAlertmanagerConfigAcceptsKlstrConfig(t *testing.T) {
+	config, err := NewAlertmanagerConfig(AlertmanagerReceiver{
+		Name:         "klstr",
+		SlackConfigs: []SlackConfig{{APIURL: "https://hooks.slack.com/services/x", SendResolved: true}},
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ValidateAlertmanagerConfig(config)
+	if err != nil {
+		t.Errorf("expected the klstr config to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,340 @@
+package manifests
+
+import (
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// LastAppliedAnnotation holds the configuration an object was last applied
+// with. It is shared with kubectl apply so that both compute the same diffs.
+const LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+const (
+	// ManagedByLabel marks the objects installed by klstr, which are the
+	// only objects it deletes.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByKlstr = "klstr"
+	// ComponentLabel names the component an object belongs to.
+	ComponentLabel = "io.klstr/component"
+)
+
+const (
+	ApplyCreated    = "created"
+	ApplyConfigured = "configured"
+	ApplyUnchanged  = "unchanged"
+)
+
+const (
+	DeleteDeleted = "deleted"
+	DeleteMissing = "missing"
+	// DeleteUnmanaged reports objects left in place as klstr does not
+	// manage them.
+	DeleteUnmanaged = "unmanaged"
+)
+
+// Applier creates or patches objects so that they match their manifests.
+// Changes are computed as a three-way diff between the last applied
+// configuration, the manifest and the live object, so that fields set by
+// the cluster or other clients are left alone.
+type Applier struct {
+	dynamic   dynamic.Interface
+	discovery discovery.DiscoveryInterface
+	mapper    meta.RESTMapper
+	// labels are added to every applied object.
+	labels map[string]string
+}
+
+func NewApplier(config *rest.Config) (*Applier, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	a := &Applier{
+		dynamic:   dyn,
+		discovery: dc,
+		labels:    map[string]string{ManagedByLabel: ManagedByKlstr},
+	}
+	err = a.refreshMapper()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// WithLabels returns an applier adding labels to the objects it applies.
+func (a *Applier) WithLabels(labels map[string]string) *Applier {
+	merged := map[string]string{}
+	for k, v := range a.labels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return &Applier{
+		dynamic:   a.dynamic,
+		discovery: a.discovery,
+		mapper:    a.mapper,
+		labels:    merged,
+	}
+}
+
+func (a *Applier) refreshMapper() error {
+	groupResources, err := restmapper.GetAPIGroupResources(a.discovery)
+	if err != nil {
+		return err
+	}
+	a.mapper = restmapper.NewDiscoveryRESTMapper(groupResources)
+	return nil
+}
+
+// restMapping maps gvk to its resource. Discovery is refreshed once when
+// the kind is unknown, as it may be served by a CRD installed since.
+func (a *Applier) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		err = a.refreshMapper()
+		if err != nil {
+			return nil, err
+		}
+		mapping, err = a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	return mapping, err
+}
+
+// ApplyObjects applies objects in order, stopping at the first error.
+func (a *Applier) ApplyObjects(namespace string, objects []runtime.Object) error {
+	for _, object := range objects {
+		_, err := a.Apply(namespace, object)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply creates object, or patches it when it exists. Namespaced objects
+// are applied in namespace. It returns one of ApplyCreated,
+// ApplyConfigured or ApplyUnchanged.
+func (a *Applier) Apply(namespace string, object runtime.Object) (string, error) {
+	desired, ri, err := a.resourceFor(namespace, object)
+	if err != nil {
+		return "", err
+	}
+	gvk := desired.GroupVersionKind()
+	labels := desired.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range a.labels {
+		labels[k] = v
+	}
+	desired.SetLabels(labels)
+	modified, err := setLastApplied(desired)
+	if err != nil {
+		return "", err
+	}
+	name := desired.GetName()
+	description := fmt.Sprintf("%s %s", gvk.Kind, name)
+	current, err := ri.Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = ri.Create(desired)
+		if err != nil {
+			return "", fmt.Errorf("unable to create %s: %v", description, err)
+		}
+		log.Infof("%s created", description)
+		return ApplyCreated, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	original := []byte(current.GetAnnotations()[LastAppliedAnnotation])
+	currentJSON, err := current.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	patchType, patch, err := threeWayPatch(gvk, original, modified, currentJSON)
+	if err != nil {
+		return "", fmt.Errorf("unable to compute patch for %s: %v", description, err)
+	}
+	if string(patch) == "{}" {
+		log.Infof("%s unchanged", description)
+		return ApplyUnchanged, nil
+	}
+	_, err = ri.Patch(name, patchType, patch)
+	if err != nil {
+		return "", fmt.Errorf("unable to patch %s: %v", description, err)
+	}
+	log.Infof("%s configured", description)
+	return ApplyConfigured, nil
+}
+
+// LastApplied returns the configuration object was last applied with, or
+// nil when object does not exist. Objects created without recording their
+// configuration are returned as they are, without the fields set by the
+// cluster.
+func (a *Applier) LastApplied(namespace string, object runtime.Object) (*unstructured.Unstructured, error) {
+	desired, ri, err := a.resourceFor(namespace, object)
+	if err != nil {
+		return nil, err
+	}
+	current, err := ri.Get(desired.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	applied, ok := current.GetAnnotations()[LastAppliedAnnotation]
+	if !ok {
+		for _, field := range []string{"resourceVersion", "uid", "selfLink", "generation", "creationTimestamp"} {
+			unstructured.RemoveNestedField(current.Object, "metadata", field)
+		}
+		unstructured.RemoveNestedField(current.Object, "status")
+		return current, nil
+	}
+	previous := &unstructured.Unstructured{}
+	err = previous.UnmarshalJSON([]byte(applied))
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// Delete deletes object when it is managed by klstr, ignoring objects that
+// do not exist. It returns whether the object was deleted, missing or left
+// in place.
+func (a *Applier) Delete(namespace string, object runtime.Object) (string, error) {
+	desired, ri, err := a.resourceFor(namespace, object)
+	if err != nil {
+		return "", err
+	}
+	current, err := ri.Get(desired.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return DeleteMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if current.GetLabels()[ManagedByLabel] != ManagedByKlstr {
+		log.Warnf("%s %s is not managed by klstr, leaving it in place", desired.GetKind(), desired.GetName())
+		return DeleteUnmanaged, nil
+	}
+	propagation := metav1.DeletePropagationForeground
+	err = ri.Delete(desired.GetName(), &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if errors.IsNotFound(err) {
+		return DeleteMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	log.Infof("%s %s deleted", desired.GetKind(), desired.GetName())
+	return DeleteDeleted, nil
+}
+
+// resourceFor returns object as unstructured, placed in namespace when its
+// kind is namespaced, along with the client for its resource.
+func (a *Applier) resourceFor(namespace string, object runtime.Object) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
+	u, err := toUnstructured(object)
+	if err != nil {
+		return nil, nil, err
+	}
+	mapping, err := a.restMapping(u.GroupVersionKind())
+	if err != nil {
+		return nil, nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return u, a.dynamic.Resource(mapping.Resource), nil
+	}
+	u.SetNamespace(namespace)
+	return u, a.dynamic.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+// threeWayPatch uses a strategic merge patch for kinds known to the client
+// scheme, which carry the merge keys of their lists, and falls back to a
+// JSON merge patch for custom resources.
+func threeWayPatch(gvk schema.GroupVersionKind, original, modified, current []byte) (types.PatchType, []byte, error) {
+	if len(original) == 0 {
+		original = []byte("{}")
+	}
+	versioned, err := scheme.Scheme.New(gvk)
+	if err == nil {
+		lookupPatchMeta, err := strategicpatch.NewPatchMetaFromStruct(versioned)
+		if err != nil {
+			return "", nil, err
+		}
+		patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, lookupPatchMeta, true)
+		return types.StrategicMergePatchType, patch, err
+	}
+	if !runtime.IsNotRegisteredError(err) {
+		return "", nil, err
+	}
+	patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+	return types.MergePatchType, patch, err
+}
+
+func toUnstructured(object runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := object.(*unstructured.Unstructured); ok {
+		return u.DeepCopy(), nil
+	}
+	gvk := object.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		gvks, _, err := scheme.Scheme.ObjectKinds(object)
+		if err != nil {
+			return nil, err
+		}
+		gvk = gvks[0]
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	// Typed objects carry empty server populated fields that are not part
+	// of the manifest.
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "status")
+	return u, nil
+}
+
+// setLastApplied records the configuration of u on itself and returns u
+// serialized along with the annotation.
+func setLastApplied(u *unstructured.Unstructured) ([]byte, error) {
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	delete(annotations, LastAppliedAnnotation)
+	if len(annotations) == 0 {
+		u.SetAnnotations(nil)
+	} else {
+		u.SetAnnotations(annotations)
+	}
+	applied, err := json.Marshal(u.Object)
+	if err != nil {
+		return nil, err
+	}
+	annotations[LastAppliedAnnotation] = string(applied)
+	u.SetAnnotations(annotations)
+	return json.Marshal(u.Object)
+}
@@ -0,0 +1,115 @@
+package manifests
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	patchOriginal = []byte(`{"metadata":{"name":"a","labels":{"app":"a","old":"x"}},"spec":{"type":"ClusterIP"}}`)
+	patchModified = []byte(`{"metadata":{"name":"a","labels":{"app":"a"}},"spec":{"type":"NodePort"}}`)
+	patchCurrent  = []byte(`{"metadata":{"name":"a","labels":{"app":"a","old":"x"}},"spec":{"type":"ClusterIP","clusterIP":"10.0.0.1"}}`)
+	expectedPatch = map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"old": nil},
+		},
+		"spec": map[string]interface{}{"type": "NodePort"},
+	}
+)
+
+func assertPatch(t *testing.T, patch []byte, expected map[string]interface{}) {
+	var got map[string]interface{}
+	err := json.Unmarshal(patch, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected patch %v, got %s", expected, patch)
+	}
+}
+
+func TestThreeWayPatchRegisteredKind(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	patchType, patch, err := threeWayPatch(gvk, patchOriginal, patchModified, patchCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patchType != types.StrategicMergePatchType {
+		t.Errorf("expected a strategic merge patch, got %s", patchType)
+	}
+	assertPatch(t, patch, expectedPatch)
+}
+
+func TestThreeWayPatchCustomKind(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "Prometheus"}
+	patchType, patch, err := threeWayPatch(gvk, patchOriginal, patchModified, patchCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patchType != types.MergePatchType {
+		t.Errorf("expected a json merge patch, got %s", patchType)
+	}
+	assertPatch(t, patch, expectedPatch)
+}
+
+func TestThreeWayPatchKeepsFieldsOutsideTheManifest(t *testing.T) {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	_, patch, err := threeWayPatch(gvk, patchModified, patchModified, patchCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the type in the manifest is reverted, the label and cluster ip
+	// the manifest does not set are kept.
+	assertPatch(t, patch, map[string]interface{}{
+		"spec": map[string]interface{}{"type": "NodePort"},
+	})
+	// Nothing changes when the live object only adds to the manifest.
+	_, patch, err = threeWayPatch(gvk, patchOriginal, patchOriginal, patchCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(patch) != "{}" {
+		t.Errorf("expected an empty patch, got %s", patch)
+	}
+}
+
+func TestSetLastApplied(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "a",
+			Annotations: map[string]string{LastAppliedAnnotation: "stale"},
+		},
+	}
+	u, err := toUnstructured(service)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.GetKind() != "Service" || u.GetAPIVersion() != "v1" {
+		t.Errorf("expected a v1 Service, got %s %s", u.GetAPIVersion(), u.GetKind())
+	}
+	if _, ok := u.Object["status"]; ok {
+		t.Errorf("expected status to be dropped")
+	}
+	_, err = setLastApplied(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied map[string]interface{}
+	err = json.Unmarshal([]byte(u.GetAnnotations()[LastAppliedAnnotation]), &applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := applied["metadata"].(map[string]interface{})
+	if _, ok := metadata["annotations"]; ok {
+		t.Errorf("expected the last applied configuration not to nest itself, got %v", metadata)
+	}
+	if _, ok := metadata["creationTimestamp"]; ok {
+		t.Errorf("expected creationTimestamp to be dropped")
+	}
+}
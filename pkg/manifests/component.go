@@ -0,0 +1,262 @@
+package manifests
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	prometheusop "github.com/coreos/prometheus-operator/pkg/client/monitoring"
+	log "github.com/sirupsen/logrus"
+	apiextnclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+type ServiceInstaller interface {
+	InstallService() error
+	// Objects returns the objects making up the component in the order
+	// they are applied.
+	Objects() ([]runtime.Object, error)
+}
+
+// MuserviceCRDComponent is the component installing the Muservice crd.
+const MuserviceCRDComponent = "muservice-crd"
+
+const (
+	GroupCore    = "core"
+	GroupIngress = "ingress"
+	GroupLogging = "logging"
+	GroupMetrics = "metrics"
+	GroupTracing = "tracing"
+)
+
+type ComponentOptions struct {
+	// Namespace is where the namespaced objects of every component are
+	// installed.
+	Namespace string
+	// Domain is the base domain platform UIs are published under. UIs
+	// are not published when it is empty.
+	Domain string
+	// ProxyProtocol has the ingress load balancer pass the client
+	// addresses with the PROXY protocol.
+	ProxyProtocol    bool
+	KubeClient       *kubernetes.Clientset
+	PrometheusClient *prometheusop.Clientset
+	ExtensionsClient *apiextnclient.Clientset
+	// Applier is nil when components are only inspected.
+	Applier *Applier
+}
+
+type ComponentFactory func(options ComponentOptions) ServiceInstaller
+
+// Component is an installable part of klstr. Components are installed
+// after the components they depend on.
+type Component struct {
+	Name         string
+	Group        string
+	Dependencies []string
+	Factory      ComponentFactory
+}
+
+var components = make(map[string]Component)
+
+// componentNames keeps registration order so that installs are stable.
+var componentNames []string
+
+func RegisterComponent(component Component) {
+	if component.Factory == nil {
+		log.Errorf("Component Factory %s does not exist", component.Name)
+		return
+	}
+	_, registered := components[component.Name]
+	if registered {
+		log.Errorf("Component %s already registered. Ignoring.", component.Name)
+		return
+	}
+	components[component.Name] = component
+	componentNames = append(componentNames, component.Name)
+}
+
+func init() {
+	RegisterComponent(Component{
+		Name:  MuserviceCRDComponent,
+		Group: GroupCore,
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewMuserviceCRDInstaller(options.ExtensionsClient, options.Applier)
+		},
+	})
+	RegisterComponent(Component{
+		Name:  "nginx-ingress",
+		Group: GroupIngress,
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewNginxIngressInstaller(options.KubeClient, options.Applier, options.Namespace, options.ProxyProtocol)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         "oklog",
+		Group:        GroupLogging,
+		Dependencies: []string{"nginx-ingress"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewOkLogInstaller(options.KubeClient, options.Applier, options.Namespace, options.Domain)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         "fluent-bit",
+		Group:        GroupLogging,
+		Dependencies: []string{"oklog"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewFluentBitInstaller(options.KubeClient, options.Applier, options.Namespace)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         "prometheus-operator",
+		Group:        GroupMetrics,
+		Dependencies: []string{"nginx-ingress"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewPrometheusOperatorInstaller(options.KubeClient, options.PrometheusClient, options.Applier, options.Namespace, options.Domain)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         "node-exporter",
+		Group:        GroupMetrics,
+		Dependencies: []string{"prometheus-operator"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewNodeExporterInstaller(options.KubeClient, options.PrometheusClient, options.Applier, options.Namespace)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         "kube-state-metrics",
+		Group:        GroupMetrics,
+		Dependencies: []string{"prometheus-operator"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewKubeStateMetricsInstaller(options.KubeClient, options.PrometheusClient, options.Applier, options.Namespace)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         "grafana",
+		Group:        GroupMetrics,
+		Dependencies: []string{"prometheus-operator", "nginx-ingress"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewGrafanaInstaller(options.KubeClient, options.Applier, options.Namespace, options.Domain)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         AlertmanagerComponent,
+		Group:        GroupMetrics,
+		Dependencies: []string{"prometheus-operator"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewAlertmanagerInstaller(options.KubeClient, options.PrometheusClient, options.Applier, options.Namespace)
+		},
+	})
+	RegisterComponent(Component{
+		Name:         "jaeger",
+		Group:        GroupTracing,
+		Dependencies: []string{"nginx-ingress"},
+		Factory: func(options ComponentOptions) ServiceInstaller {
+			return NewJaegerInstaller(options.KubeClient, options.Applier, options.Namespace, options.Domain)
+		},
+	})
+}
+
+// Installer returns the installer of the component, labelling the objects
+// it applies with the component name.
+func (c Component) Installer(options ComponentOptions) ServiceInstaller {
+	if options.Applier != nil {
+		options.Applier = options.Applier.WithLabels(map[string]string{ComponentLabel: c.Name})
+	}
+	return c.Factory(options)
+}
+
+func GetComponent(name string) (Component, bool) {
+	component, ok := components[name]
+	return component, ok
+}
+
+// ResolveComponents returns the components to install in dependency order.
+// selected defaults to every registered component and pulls in the
+// dependencies of the selected components. skip takes component or group
+// names; skipped dependencies are assumed to be installed already.
+func ResolveComponents(selected, skip []string) ([]Component, error) {
+	skipped := map[string]bool{}
+	for _, name := range skip {
+		names, err := expandComponentName(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			skipped[n] = true
+		}
+	}
+	if len(selected) == 0 {
+		selected = componentNames
+	}
+	var wanted []string
+	for _, name := range selected {
+		names, err := expandComponentName(name)
+		if err != nil {
+			return nil, err
+		}
+		wanted = append(wanted, names...)
+	}
+
+	var ordered []Component
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle between components: %s", strings.Join(append(path, name), " -> "))
+		}
+		component, ok := components[name]
+		if !ok {
+			return fmt.Errorf("component %s depends on unknown component %s", path[len(path)-1], name)
+		}
+		state[name] = visiting
+		for _, dep := range component.Dependencies {
+			err := visit(dep, append(path, name))
+			if err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		if skipped[name] {
+			log.Infof("Skipping component %s", name)
+			return nil
+		}
+		ordered = append(ordered, component)
+		return nil
+	}
+	for _, name := range wanted {
+		err := visit(name, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// expandComponentName resolves a component or group name into component
+// names.
+func expandComponentName(name string) ([]string, error) {
+	if _, ok := components[name]; ok {
+		return []string{name}, nil
+	}
+	var names []string
+	for _, n := range componentNames {
+		if components[n].Group == name {
+			names = append(names, n)
+		}
+	}
+	if len(names) == 0 {
+		available := append([]string{}, componentNames...)
+		sort.Strings(available)
+		return nil, fmt.Errorf("Invalid Component Name: %s. Must be a group or one of: %s", name, strings.Join(available, ", "))
+	}
+	return names, nil
+}
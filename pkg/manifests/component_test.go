@@ -0,0 +1,64 @@
+package manifests
+
+import (
+	"testing"
+)
+
+func componentIndex(components []Component) map[string]int {
+	index := map[string]int{}
+	for i, c := range components {
+		index[c.Name] = i
+	}
+	return index
+}
+
+func TestResolveComponentsOrdersDependencies(t *testing.T) {
+	components, err := ResolveComponents(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := componentIndex(components)
+	if len(index) != len(componentNames) {
+		t.Fatalf("expected %d components, got %d", len(componentNames), len(index))
+	}
+	for _, c := range components {
+		for _, dep := range c.Dependencies {
+			if index[dep] > index[c.Name] {
+				t.Errorf("component %s installed before its dependency %s", c.Name, dep)
+			}
+		}
+	}
+}
+
+func TestResolveComponentsPullsInDependencies(t *testing.T) {
+	components, err := ResolveComponents([]string{"grafana"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := componentIndex(components)
+	if _, ok := index["prometheus-operator"]; !ok {
+		t.Errorf("expected prometheus-operator to be installed for grafana")
+	}
+	if _, ok := index["oklog"]; ok {
+		t.Errorf("expected oklog not to be installed")
+	}
+}
+
+func TestResolveComponentsSkipsGroups(t *testing.T) {
+	components, err := ResolveComponents(nil, []string{GroupMetrics})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range components {
+		if c.Group == GroupMetrics {
+			t.Errorf("expected metrics component %s to be skipped", c.Name)
+		}
+	}
+}
+
+func TestResolveComponentsRejectsUnknown(t *testing.T) {
+	_, err := ResolveComponents([]string{"nope"}, nil)
+	if err == nil {
+		t.Errorf("expected an error for an unknown component")
+	}
+}
@@ -0,0 +1,89 @@
+package manifests
+
+import (
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FluentBitInstaller runs fluent-bit on every node, shipping container
+// logs enriched with their kubernetes metadata to the oklog ingest service.
+type FluentBitInstaller struct {
+	cs        *kubernetes.Clientset
+	applier   *Applier
+	namespace string
+}
+
+func NewFluentBitInstaller(cs *kubernetes.Clientset, applier *Applier, namespace string) *FluentBitInstaller {
+	return &FluentBitInstaller{cs: cs, applier: applier, namespace: namespace}
+}
+
+func (fi *FluentBitInstaller) InstallService() error {
+	objects, err := fi.Objects()
+	if err != nil {
+		return err
+	}
+	return fi.applier.ApplyObjects(fi.namespace, objects)
+}
+
+func (fi *FluentBitInstaller) Objects() ([]runtime.Object, error) {
+	objects, err := getFluentBitRbacSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := getFluentBitConfigMapSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	daemonSet, err := getFluentBitDaemonSetSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, configMap, daemonSet)
+	setSubjectsNamespace(objects, fi.namespace)
+	return objects, nil
+}
+
+func (fi *FluentBitInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "log shipping", Component: "fluent-bit"}
+	daemonSetStatus(fi.cs, fi.namespace, "fluent-bit", &status)
+	return status
+}
+
+func getFluentBitRbacSpecFromFile() ([]runtime.Object, error) {
+	data, err := assets.ReadFile("logging/fb-rbac.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	return schemaDecoder.MultiDecode()
+}
+
+func getFluentBitConfigMapSpecFromFile() (*corev1.ConfigMap, error) {
+	data, err := assets.ReadFile("logging/fb-config-map.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return object.(*corev1.ConfigMap), nil
+}
+
+func getFluentBitDaemonSetSpecFromFile() (*appsv1.DaemonSet, error) {
+	data, err := assets.ReadFile("logging/fb-ds.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return object.(*appsv1.DaemonSet), nil
+}
@@ -1,90 +1,154 @@
 package manifests
 
 import (
-	"io/ioutil"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
 
+	"github.com/klstr/klstr/pkg/assets"
 	"github.com/klstr/klstr/pkg/util"
-	log "github.com/sirupsen/logrus"
+	"github.com/klstr/klstr/pkg/version"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
-	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 )
 
 type GrafanaInstaller struct {
-	cs *kubernetes.Clientset
+	cs        *kubernetes.Clientset
+	applier   *Applier
+	namespace string
+	domain    string
 }
 
-func NewGrafanaInstaller(cs *kubernetes.Clientset) *GrafanaInstaller {
-	return &GrafanaInstaller{cs: cs}
+func NewGrafanaInstaller(cs *kubernetes.Clientset, applier *Applier, namespace, domain string) *GrafanaInstaller {
+	return &GrafanaInstaller{cs: cs, applier: applier, namespace: namespace, domain: domain}
 }
 
 func (gi *GrafanaInstaller) InstallService() error {
-	err := ensureGrafanaDeployment(gi.cs)
+	objects, err := gi.Objects()
 	if err != nil {
 		return err
 	}
-	return ensureGrafanaService(gi.cs)
+	return gi.applier.ApplyObjects(gi.namespace, objects)
 }
 
-const GrafanaImage = "grafana/grafana:5.2.2"
-
-func ensureGrafanaDeployment(cs *kubernetes.Clientset) error {
-	di := cs.AppsV1().Deployments("default")
-	deploymentList, err := di.List(metav1.ListOptions{LabelSelector: "app=grafana"})
+// Objects returns grafana along with the config maps provisioning its
+// prometheus datasource and the klstr dashboards.
+func (gi *GrafanaInstaller) Objects() ([]runtime.Object, error) {
+	provisioning, err := getGrafanaProvisioningSpecFromFile()
 	if err != nil {
-		log.Errorf("unable to list any deployments")
-		return err
+		return nil, err
+	}
+	dashboards, err := getGrafanaDashboards()
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := getGrafanaDeplomentSpecFromFile()
+	if err != nil {
+		return nil, err
 	}
-	if len(deploymentList.Items) > 0 {
-		log.Infof("Found deployment %+v", deploymentList.Items[0])
-	} else {
-		log.Infof("creating deployment")
-		err = createGrafanaDeployment(di)
+	service, err := getGrafanaServiceSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	var configMaps []*corev1.ConfigMap
+	for _, object := range provisioning {
+		configMap, ok := object.(*corev1.ConfigMap)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %s in grafana provisioning", object.GetObjectKind().GroupVersionKind().Kind)
+		}
+		configMaps = append(configMaps, configMap)
+		objects = append(objects, configMap)
+	}
+	configMaps = append(configMaps, dashboards)
+	// Grafana only reads its provisioning on startup.
+	setConfigChecksum(&deployment.Spec.Template, configMaps)
+	objects = append(objects, dashboards, deployment, service)
+	if gi.domain != "" {
+		ingress, err := getIngressSpecFromFile("monitoring/grafana-ingress.yaml", "grafana", gi.domain)
 		if err != nil {
-			log.Errorf("unable to create deployment %v", err)
-			return err
+			return nil, err
 		}
+		objects = append(objects, ingress)
 	}
-	return nil
+	return objects, nil
 }
-func ensureGrafanaService(cs *kubernetes.Clientset) error {
-	si := cs.CoreV1().Services("default")
-	s, err := si.Get("grafana", metav1.GetOptions{})
-	if err == nil {
-		log.Infof("Found grafana service %+v", s)
-		return nil
-	}
-	sobj, err := getGrafanaServiceSpecFromFile()
+
+func (gi *GrafanaInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "grafana", Component: "grafana"}
+	deploymentStatus(gi.cs, gi.namespace, "grafana", &status)
+	return status
+}
+
+const GrafanaImage = "grafana/grafana:5.2.2"
+
+// ConfigChecksumAnnotation holds the checksum of the config maps a pod
+// reads on startup, so that changing them rolls the pods.
+const ConfigChecksumAnnotation = "io.klstr/config-checksum"
+
+// GrafanaDashboards are the dashboards under k8s/monitoring/dashboards
+// provisioned into grafana.
+var GrafanaDashboards = []string{
+	"cluster.json",
+	"nodes.json",
+	"muservices.json",
+}
+
+func getGrafanaProvisioningSpecFromFile() ([]runtime.Object, error) {
+	data, err := assets.ReadFile("monitoring/grafana-provisioning.yaml")
 	if err != nil {
-		log.Info("unable to decode from service file ", err)
-		return err
+		return nil, err
 	}
-	s, err = si.Create(sobj)
-	if err != nil {
-		log.Errorf("unable to create service %s", err)
-		return err
+	schemaDecoder := util.NewSchemaDecoder(data)
+	return schemaDecoder.MultiDecode()
+}
+
+// getGrafanaDashboards bundles the klstr dashboards into the config map
+// grafana provisions them from, recording the klstr release they ship
+// with.
+func getGrafanaDashboards() (*corev1.ConfigMap, error) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "grafana-dashboards",
+			Labels:      map[string]string{"app": "grafana"},
+			Annotations: map[string]string{VersionAnnotation: version.Version},
+		},
+		Data: map[string]string{},
 	}
-	log.Infof("Created service %+v", s)
-	return nil
+	for _, name := range GrafanaDashboards {
+		data, err := assets.ReadFile("monitoring/dashboards/" + name)
+		if err != nil {
+			return nil, err
+		}
+		configMap.Data[name] = string(data)
+	}
+	return configMap, nil
 }
 
-func createGrafanaDeployment(di typedappsv1.DeploymentInterface) error {
-	depObj, err := getGrafanaDeplomentSpecFromFile()
-	if err != nil {
-		return err
+func setConfigChecksum(template *corev1.PodTemplateSpec, configMaps []*corev1.ConfigMap) {
+	hash := sha256.New()
+	for _, configMap := range configMaps {
+		var keys []string
+		for key := range configMap.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(hash, "%s/%s\n%s\n", configMap.Name, key, configMap.Data[key])
+		}
 	}
-	dep, err := di.Create(depObj)
-	if err != nil {
-		return err
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
 	}
-	log.Infof("Created a deployment ", dep)
-	return nil
+	template.Annotations[ConfigChecksumAnnotation] = hex.EncodeToString(hash.Sum(nil))
 }
 
 func getGrafanaServiceSpecFromFile() (*corev1.Service, error) {
-	data, err := ioutil.ReadFile("k8s/monitoring/grafana-service.yaml")
+	data, err := assets.ReadFile("monitoring/grafana-service.yaml")
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +161,7 @@ func getGrafanaServiceSpecFromFile() (*corev1.Service, error) {
 }
 
 func getGrafanaDeplomentSpecFromFile() (*appsv1.Deployment, error) {
-	data, err := ioutil.ReadFile("k8s/monitoring/grafana-deployment.yaml")
+	data, err := assets.ReadFile("monitoring/grafana-deployment.yaml")
 	if err != nil {
 		return nil, err
 	}
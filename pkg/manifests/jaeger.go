@@ -0,0 +1,59 @@
+package manifests
+
+import (
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+type JaegerInstaller struct {
+	cs        *kubernetes.Clientset
+	applier   *Applier
+	namespace string
+	domain    string
+}
+
+func NewJaegerInstaller(cs *kubernetes.Clientset, applier *Applier, namespace, domain string) *JaegerInstaller {
+	return &JaegerInstaller{cs: cs, applier: applier, namespace: namespace, domain: domain}
+}
+
+func (ji *JaegerInstaller) InstallService() error {
+	objects, err := ji.Objects()
+	if err != nil {
+		return err
+	}
+	return ji.applier.ApplyObjects(ji.namespace, objects)
+}
+
+// Objects returns the all-in-one jaeger deployment along with the agent,
+// collector and query services. The query UI is published at jaeger.<domain>.
+func (ji *JaegerInstaller) Objects() ([]runtime.Object, error) {
+	objects, err := getJaegerSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	if ji.domain != "" {
+		ingress, err := getIngressSpecFromFile("tracing/jaeger-ingress.yaml", "jaeger", ji.domain)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, ingress)
+	}
+	return objects, nil
+}
+
+func (ji *JaegerInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "tracing", Component: "jaeger"}
+	deploymentStatus(ji.cs, ji.namespace, "jaeger", &status)
+	return status
+}
+
+func getJaegerSpecFromFile() ([]runtime.Object, error) {
+	data, err := assets.ReadFile("tracing/jaeger.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	return schemaDecoder.MultiDecode()
+}
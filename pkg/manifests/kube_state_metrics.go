@@ -0,0 +1,67 @@
+package manifests
+
+import (
+	prometheusop "github.com/coreos/prometheus-operator/pkg/client/monitoring"
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+type KubeStateMetricsInstaller struct {
+	cs        *kubernetes.Clientset
+	ps        *prometheusop.Clientset
+	applier   *Applier
+	namespace string
+}
+
+func NewKubeStateMetricsInstaller(
+	cs *kubernetes.Clientset,
+	ps *prometheusop.Clientset,
+	applier *Applier,
+	namespace string,
+) *KubeStateMetricsInstaller {
+	return &KubeStateMetricsInstaller{
+		cs:        cs,
+		ps:        ps,
+		applier:   applier,
+		namespace: namespace,
+	}
+}
+
+func (ki *KubeStateMetricsInstaller) InstallService() error {
+	objects, err := ki.Objects()
+	if err != nil {
+		return err
+	}
+	return applyMonitoredObjects(ki.ps, ki.applier, ki.namespace, objects)
+}
+
+func (ki *KubeStateMetricsInstaller) Objects() ([]runtime.Object, error) {
+	objects, err := getKubeStateMetricsSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	monitors, err := getServiceMonitorsSpecFromFile("monitoring/kube-state-metrics-service-monitor.yaml")
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, monitors...)
+	setSubjectsNamespace(objects, ki.namespace)
+	return objects, nil
+}
+
+func (ki *KubeStateMetricsInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "object metrics", Component: "kube-state-metrics"}
+	deploymentStatus(ki.cs, ki.namespace, "kube-state-metrics", &status)
+	return status
+}
+
+func getKubeStateMetricsSpecFromFile() ([]runtime.Object, error) {
+	data, err := assets.ReadFile("monitoring/kube-state-metrics.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	return schemaDecoder.MultiDecode()
+}
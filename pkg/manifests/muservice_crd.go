@@ -0,0 +1,105 @@
+package manifests
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
+	"github.com/klstr/klstr/pkg/version"
+	log "github.com/sirupsen/logrus"
+	apiextnv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextnclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+type MuserviceCRDInstaller struct {
+	es      *apiextnclient.Clientset
+	applier *Applier
+}
+
+func NewMuserviceCRDInstaller(es *apiextnclient.Clientset, applier *Applier) *MuserviceCRDInstaller {
+	return &MuserviceCRDInstaller{es: es, applier: applier}
+}
+
+func (mi *MuserviceCRDInstaller) InstallService() error {
+	objects, err := mi.Objects()
+	if err != nil {
+		return err
+	}
+	err = mi.applier.ApplyObjects("", objects)
+	if err != nil {
+		return err
+	}
+	return waitForCRDEstablished(mi.es, MuserviceCRDName)
+}
+
+func (mi *MuserviceCRDInstaller) Objects() ([]runtime.Object, error) {
+	crd, err := getMuserviceCRDSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Object{crd}, nil
+}
+
+func (mi *MuserviceCRDInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "muservices", Component: "muservice-crd"}
+	crd, err := mi.es.ApiextensionsV1beta1().CustomResourceDefinitions().Get(MuserviceCRDName, metav1.GetOptions{})
+	if err != nil {
+		setObjectError(&status, "crd", MuserviceCRDName, err)
+		return status
+	}
+	status.Installed = true
+	status.Version = crd.Annotations[VersionAnnotation]
+	status.Ready = crdEstablished(crd)
+	if !status.Ready {
+		status.Message = fmt.Sprintf("crd %s is not established", MuserviceCRDName)
+	}
+	return status
+}
+
+const MuserviceCRDName = "muservices.io.klstr"
+
+func waitForCRDEstablished(es *apiextnclient.Clientset, name string) error {
+	ci := es.ApiextensionsV1beta1().CustomResourceDefinitions()
+	return wait.Poll(500*time.Millisecond, 60*time.Second, func() (bool, error) {
+		crd, err := ci.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if crdEstablished(crd) {
+			return true, nil
+		}
+		log.Infof("Waiting for crd %s to be established", name)
+		return false, nil
+	})
+}
+
+func crdEstablished(crd *apiextnv1beta1.CustomResourceDefinition) bool {
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextnv1beta1.Established && cond.Status == apiextnv1beta1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func getMuserviceCRDSpecFromFile() (*apiextnv1beta1.CustomResourceDefinition, error) {
+	data, err := assets.ReadFile("crd/muservice.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode(&apiextnv1beta1.CustomResourceDefinition{})
+	if err != nil {
+		return nil, err
+	}
+	crd := object.(*apiextnv1beta1.CustomResourceDefinition)
+	if crd.Annotations == nil {
+		crd.Annotations = map[string]string{}
+	}
+	crd.Annotations[VersionAnnotation] = version.Version
+	return crd, nil
+}
@@ -0,0 +1,167 @@
+package manifests
+
+import (
+	"fmt"
+
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	extnv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+type NginxIngressInstaller struct {
+	cs        *kubernetes.Clientset
+	applier   *Applier
+	namespace string
+	// proxyProtocol has the load balancer pass the client addresses to
+	// nginx with the PROXY protocol.
+	proxyProtocol bool
+}
+
+func NewNginxIngressInstaller(cs *kubernetes.Clientset, applier *Applier, namespace string, proxyProtocol bool) *NginxIngressInstaller {
+	return &NginxIngressInstaller{cs: cs, applier: applier, namespace: namespace, proxyProtocol: proxyProtocol}
+}
+
+func (ni *NginxIngressInstaller) InstallService() error {
+	objects, err := ni.Objects()
+	if err != nil {
+		return err
+	}
+	return ni.applier.ApplyObjects(ni.namespace, objects)
+}
+
+func (ni *NginxIngressInstaller) Objects() ([]runtime.Object, error) {
+	objects, err := getNginxMandatorySpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range []string{"nginx/nginx-service.yaml", "nginx/nginx-metrics-service.yaml"} {
+		service, err := getNginxServiceSpecFromFile(file)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, service)
+	}
+	setSubjectsNamespace(objects, ni.namespace)
+	if ni.proxyProtocol {
+		enableProxyProtocol(objects)
+	}
+	return objects, nil
+}
+
+// enableProxyProtocol has aws load balancers send the PROXY protocol, and
+// nginx expect it. Load balancers of other clouds do not support it.
+func enableProxyProtocol(objects []runtime.Object) {
+	for _, object := range objects {
+		switch o := object.(type) {
+		case *corev1.ConfigMap:
+			if o.Name != "nginx-configuration" {
+				continue
+			}
+			if o.Data == nil {
+				o.Data = map[string]string{}
+			}
+			o.Data["use-proxy-protocol"] = "true"
+		case *corev1.Service:
+			if o.Name != NginxServiceName {
+				continue
+			}
+			if o.Annotations == nil {
+				o.Annotations = map[string]string{}
+			}
+			o.Annotations["service.beta.kubernetes.io/aws-load-balancer-proxy-protocol"] = "*"
+		}
+	}
+}
+
+func (ni *NginxIngressInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "ingress", Component: "nginx-ingress"}
+	deploymentStatus(ni.cs, ni.namespace, NginxControllerName, &status)
+	return status
+}
+
+const (
+	NginxControllerName = "nginx-ingress-controller"
+	NginxServiceName    = "ingress-nginx"
+)
+
+// IngressAddress returns the load balancer address of the ingress
+// controller, or an empty string until the cloud provider assigns one.
+func IngressAddress(cs kubernetes.Interface, namespace string) (string, error) {
+	service, err := cs.CoreV1().Services(namespace).Get(NginxServiceName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			return ingress.Hostname, nil
+		}
+		if ingress.IP != "" {
+			return ingress.IP, nil
+		}
+	}
+	return "", nil
+}
+
+// IngressHosts returns the hosts the ingresses among objects are
+// published at.
+func IngressHosts(objects []runtime.Object) []string {
+	var hosts []string
+	for _, object := range objects {
+		ingress, ok := object.(*extnv1beta1.Ingress)
+		if !ok {
+			continue
+		}
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host != "" {
+				hosts = append(hosts, rule.Host)
+			}
+		}
+	}
+	return hosts
+}
+
+// getIngressSpecFromFile reads the ingress of a platform UI and publishes
+// it at subdomain of domain.
+func getIngressSpecFromFile(file, subdomain, domain string) (*extnv1beta1.Ingress, error) {
+	data, err := assets.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	ingress := object.(*extnv1beta1.Ingress)
+	if len(ingress.Spec.Rules) != 1 {
+		return nil, fmt.Errorf("%s: expected a single ingress rule, found %d", file, len(ingress.Spec.Rules))
+	}
+	ingress.Spec.Rules[0].Host = fmt.Sprintf("%s.%s", subdomain, domain)
+	return ingress, nil
+}
+
+func getNginxMandatorySpecFromFile() ([]runtime.Object, error) {
+	data, err := assets.ReadFile("nginx/nginx-mandatory.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	return schemaDecoder.MultiDecode()
+}
+
+func getNginxServiceSpecFromFile(file string) (*corev1.Service, error) {
+	data, err := assets.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	object, err := schemaDecoder.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return object.(*corev1.Service), nil
+}
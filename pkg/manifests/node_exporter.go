@@ -0,0 +1,65 @@
+package manifests
+
+import (
+	prometheusop "github.com/coreos/prometheus-operator/pkg/client/monitoring"
+	"github.com/klstr/klstr/pkg/assets"
+	"github.com/klstr/klstr/pkg/util"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+type NodeExporterInstaller struct {
+	cs        *kubernetes.Clientset
+	ps        *prometheusop.Clientset
+	applier   *Applier
+	namespace string
+}
+
+func NewNodeExporterInstaller(
+	cs *kubernetes.Clientset,
+	ps *prometheusop.Clientset,
+	applier *Applier,
+	namespace string,
+) *NodeExporterInstaller {
+	return &NodeExporterInstaller{
+		cs:        cs,
+		ps:        ps,
+		applier:   applier,
+		namespace: namespace,
+	}
+}
+
+func (ni *NodeExporterInstaller) InstallService() error {
+	objects, err := ni.Objects()
+	if err != nil {
+		return err
+	}
+	return applyMonitoredObjects(ni.ps, ni.applier, ni.namespace, objects)
+}
+
+func (ni *NodeExporterInstaller) Objects() ([]runtime.Object, error) {
+	objects, err := getNodeExporterSpecFromFile()
+	if err != nil {
+		return nil, err
+	}
+	monitors, err := getServiceMonitorsSpecFromFile("monitoring/node-exporter-service-monitor.yaml")
+	if err != nil {
+		return nil, err
+	}
+	return append(objects, monitors...), nil
+}
+
+func (ni *NodeExporterInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "node metrics", Component: "node-exporter"}
+	daemonSetStatus(ni.cs, ni.namespace, "node-exporter", &status)
+	return status
+}
+
+func getNodeExporterSpecFromFile() ([]runtime.Object, error) {
+	data, err := assets.ReadFile("monitoring/node-exporter.yaml")
+	if err != nil {
+		return nil, err
+	}
+	schemaDecoder := util.NewSchemaDecoder(data)
+	return schemaDecoder.MultiDecode()
+}
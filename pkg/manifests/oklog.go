@@ -2,84 +2,65 @@ package manifests
 
 import (
 	"fmt"
-	"io/ioutil"
 
+	"github.com/klstr/klstr/pkg/assets"
 	"github.com/klstr/klstr/pkg/util"
-	log "github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
-	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
-	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
 type OkLogInstaller struct {
-	cs *kubernetes.Clientset
+	cs        *kubernetes.Clientset
+	applier   *Applier
+	namespace string
+	domain    string
 }
 
-func NewOkLogInstaller(cs *kubernetes.Clientset) *OkLogInstaller {
-	return &OkLogInstaller{cs: cs}
+func NewOkLogInstaller(cs *kubernetes.Clientset, applier *Applier, namespace, domain string) *OkLogInstaller {
+	return &OkLogInstaller{cs: cs, applier: applier, namespace: namespace, domain: domain}
 }
 
 func (oi *OkLogInstaller) InstallService() error {
-	err := ensureOkLogStatefulSet(oi.cs)
+	objects, err := oi.Objects()
 	if err != nil {
 		return err
 	}
-	return ensureOkLogService(oi.cs)
+	return oi.applier.ApplyObjects(oi.namespace, objects)
 }
 
-func ensureOkLogStatefulSet(cs *kubernetes.Clientset) error {
-	si := cs.AppsV1().StatefulSets("default")
-	statefulList, err := si.List(metav1.ListOptions{LabelSelector: "app=oklog"})
+func (oi *OkLogInstaller) Objects() ([]runtime.Object, error) {
+	statefulSet, err := getOkLogStatefulSetSpecFromFile()
 	if err != nil {
-		log.Errorf("unable to list any statefulset")
-		return err
+		return nil, err
+	}
+	service, err := getOkLogServiceSpecFromFile()
+	if err != nil {
+		return nil, err
 	}
-	if len(statefulList.Items) > 0 {
-		log.Infof("Found oklog statefulset: %+v", statefulList.Items[0])
-	} else {
-		log.Infof("creating oklog statefulset")
-		err = createOkLogStatefulSet(si)
+	objects := []runtime.Object{statefulSet, service}
+	if oi.domain != "" {
+		ingress, err := getIngressSpecFromFile("logging/oklog-ingress.yaml", "logs", oi.domain)
 		if err != nil {
-			log.Errorf("unable to create stateful set %v", err)
-			return err
+			return nil, err
 		}
+		objects = append(objects, ingress)
 	}
-	return nil
+	return objects, nil
 }
 
-func ensureOkLogService(cs *kubernetes.Clientset) error {
-	si := cs.CoreV1().Services("default")
-	s, err := si.Get("oklog", metav1.GetOptions{})
-	if err == nil {
-		log.Infof("Found oklog service %+v", s)
-		return nil
-	}
-	err = createOkLogService(si)
-	log.Info("Created oklog service %+v", s)
-	return nil
-}
-
-func createOkLogStatefulSet(si typedappsv1.StatefulSetInterface) error {
-	ssObj, err := getOkLogStatefulSetSpecFromFile()
-	if err != nil {
-		return err
-	}
-	sset, err := si.Create(ssObj)
-	if err != nil {
-		log.Errorf("unable to create oklog deployment %v", err)
-		return err
-	}
-	log.Infof("Created oklog statefulset %+v", sset)
-	return nil
+func (oi *OkLogInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "logging", Component: "oklog"}
+	statefulSetStatus(oi.cs, oi.namespace, "oklog", &status)
+	return status
 }
 
 const OkLogImage = "oklog/oklog:v0.3.2"
 
 func getOkLogStatefulSetSpecFromFile() (*appsv1.StatefulSet, error) {
-	data, err := ioutil.ReadFile("k8s/logging/oklog-ss.yaml")
+	data, err := assets.ReadFile("logging/oklog-ss.yaml")
 	if err != nil {
 		return nil, err
 	}
@@ -110,22 +91,8 @@ func buildOkLogArgs(object *appsv1.StatefulSet) {
 	object.Spec.Template.Spec.Containers[0].Args = args
 }
 
-func createOkLogService(si typedcorev1.ServiceInterface) error {
-	svcObj, err := getOkLogServiceSpecFromFile()
-	if err != nil {
-		return err
-	}
-	svc, err := si.Create(svcObj)
-	if err != nil {
-		log.Errorf("unable to create oklog service %s", err)
-		return err
-	}
-	log.Infof("Created service %+v", svc)
-	return nil
-}
-
 func getOkLogServiceSpecFromFile() (*corev1.Service, error) {
-	data, err := ioutil.ReadFile("k8s/logging/oklog-service.yaml")
+	data, err := assets.ReadFile("logging/oklog-service.yaml")
 	if err != nil {
 		return nil, err
 	}
@@ -1,233 +1,197 @@
 package manifests
 
 import (
-	"io/ioutil"
-
 	prometheusop "github.com/coreos/prometheus-operator/pkg/client/monitoring"
 	prometheusopv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
 	k8sutil "github.com/coreos/prometheus-operator/pkg/k8sutil"
+	"github.com/klstr/klstr/pkg/assets"
 	"github.com/klstr/klstr/pkg/util"
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	extnv1beta1 "k8s.io/api/extensions/v1beta1"
 	rbacv1beta1 "k8s.io/api/rbac/v1beta1"
-
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 )
 
 type PrometheusOperatorInstaller struct {
-	cs *kubernetes.Clientset
-	ps *prometheusop.Clientset
+	cs        *kubernetes.Clientset
+	ps        *prometheusop.Clientset
+	applier   *Applier
+	namespace string
+	domain    string
 }
 
 func NewPrometheusOperatorInstaller(
 	cs *kubernetes.Clientset,
 	ps *prometheusop.Clientset,
+	applier *Applier,
+	namespace string,
+	domain string,
 ) *PrometheusOperatorInstaller {
 	return &PrometheusOperatorInstaller{
-		cs: cs,
-		ps: ps,
+		cs:        cs,
+		ps:        ps,
+		applier:   applier,
+		namespace: namespace,
+		domain:    domain,
 	}
 }
 
+// InstallService applies the operator along with the prometheus it runs.
+// The Prometheus and ServiceMonitor kinds are served by CRDs the operator
+// registers once it is up, so they are waited for before prometheus and
+// its service monitors are applied.
 func (pi *PrometheusOperatorInstaller) InstallService() error {
-	err := ensurePrometheusOperator(pi.cs)
+	objects, err := pi.Objects()
 	if err != nil {
 		return err
 	}
-	err = ensurePrometheusRbac(pi.cs)
+	return applyMonitoredObjects(pi.ps, pi.applier, pi.namespace, objects)
+}
+
+func (pi *PrometheusOperatorInstaller) Objects() ([]runtime.Object, error) {
+	var objects []runtime.Object
+	operator, err := getPrometheusOperatorSpecFromFile()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = ensurePrometheusPersisted(pi.ps)
+	objects = append(objects, operator...)
+	rbac, err := getPrometheusRbacSpecFromFile()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return ensurePrometheusService(pi.cs)
-}
-
-func ensurePrometheusOperator(cs *kubernetes.Clientset) error {
-	di := cs.AppsV1().Deployments("default")
-	deploymentList, err := di.List(metav1.ListOptions{
-		LabelSelector: "k8s-app=prometheus-operator",
-	})
+	objects = append(objects, rbac...)
+	service, err := getPrometheusServiceSpecFromFile()
 	if err != nil {
-		log.Errorf("unable to list any deployments")
-		return err
+		return nil, err
 	}
-	if len(deploymentList.Items) > 0 {
-		log.Infof("Found deployment %+v", deploymentList.Items[0])
-	} else {
-		log.Infof("creating prometheus operator")
-		err = createPrometheusOperator(cs)
+	objects = append(objects, service)
+	if pi.domain != "" {
+		ingress, err := getIngressSpecFromFile("monitoring/prometheus-ingress.yaml", "prometheus", pi.domain)
 		if err != nil {
-			log.Errorf("unable to create prometheus operator %s", err)
-			return err
+			return nil, err
 		}
+		objects = append(objects, ingress)
 	}
-	return nil
-}
-
-func ensurePrometheusRbac(cs *kubernetes.Clientset) error {
-	crbi := cs.RbacV1beta1().ClusterRoleBindings()
-	crbList, err := crbi.List(metav1.ListOptions{
-		FieldSelector: "metadata.name=prometheus-operator",
-	})
+	prometheus, err := getPrometheusPersistedSpecFromFile()
 	if err != nil {
-		log.Errorf("unable to list any cluster role bindings")
-		return err
+		return nil, err
 	}
-	if len(crbList.Items) > 0 {
-		log.Infof("Found cluster role binding %+v", crbList.Items[0])
-	} else {
-		log.Infof("creating prometheus rbac")
-		err := createPrometheusRbac(cs)
-		if err != nil {
-			log.Errorf("unable to create prometheus rbac %s", err)
-			return err
+	if prometheus.Spec.Alerting != nil {
+		for i := range prometheus.Spec.Alerting.Alertmanagers {
+			prometheus.Spec.Alerting.Alertmanagers[i].Namespace = pi.namespace
 		}
 	}
-	return nil
-}
-
-func ensurePrometheusPersisted(ps *prometheusop.Clientset) error {
-	log.Info("Waiting for Prometheus CRD to be ready...")
-	k8sutil.WaitForCRDReady(
-		ps.MonitoringV1().Prometheuses("default").List,
-	)
-	log.Info("Wait over, resuming")
-	pi := ps.MonitoringV1().Prometheuses("default")
-	pList, err := pi.List(metav1.ListOptions{
-		FieldSelector: "metadata.name=prometheus2",
-	})
-	if err != nil {
-		log.Errorf("unable to list any prometheuses")
-		return err
-	}
-	plItems := pList.(*prometheusopv1.PrometheusList).Items
-	if len(plItems) > 0 {
-		log.Infof("Found prometheus %+v", plItems[0])
-	} else {
-		log.Info("creating prometheus")
-		err := createPrometheusPersisted(ps)
+	objects = append(objects, prometheus)
+	for _, file := range []string{"monitoring/kubernetes-service-monitors.yaml", "monitoring/nginx-service-monitor.yaml"} {
+		monitors, err := getServiceMonitorsSpecFromFile(file)
 		if err != nil {
-			log.Errorf("unable to create prometheus persisted %s", err)
+			return nil, err
 		}
+		objects = append(objects, monitors...)
 	}
-	return nil
+	setSubjectsNamespace(objects, pi.namespace)
+	return objects, nil
 }
 
-func ensurePrometheusService(cs *kubernetes.Clientset) error {
-	si := cs.CoreV1().Services("default")
-	serviceList, err := si.List(metav1.ListOptions{
-		FieldSelector: "metadata.name=prometheus",
-	})
-	if err != nil {
-		log.Errorf("unable to list any services")
-		return err
+// Status reports the operator deployment along with the prometheus
+// statefulset the operator creates for prometheus2.
+func (pi *PrometheusOperatorInstaller) Status() ComponentStatus {
+	status := ComponentStatus{Name: "monitoring", Component: "prometheus-operator"}
+	deploymentStatus(pi.cs, pi.namespace, "prometheus-operator", &status)
+	if !status.Ready {
+		return status
 	}
-	if len(serviceList.Items) > 0 {
-		log.Info("Found service %+v", serviceList.Items[0])
-	} else {
-		log.Info("creating prometheus service")
-		err := createPrometheusService(cs)
-		if err != nil {
-			log.Errorf("unable to create prometheus service %s", err)
-		}
-	}
-	return nil
+	prometheus := ComponentStatus{}
+	statefulSetStatus(pi.cs, pi.namespace, "prometheus-prometheus2", &prometheus)
+	status.Ready = prometheus.Ready
+	status.Message = prometheus.Message
+	return status
 }
 
-func createPrometheusOperator(cs *kubernetes.Clientset) error {
-	objects, err := getPrometheusOperatorSpecFromFile()
-	if err != nil {
-		return err
-	}
+// setSubjectsNamespace moves the service account subjects of role bindings
+// to namespace, along with the service accounts themselves.
+func setSubjectsNamespace(objects []runtime.Object, namespace string) {
 	for _, object := range objects {
-		err = createObject(cs, object)
-		if err != nil {
-			log.Errorf("unable to create object: %+v", object)
+		var subjects []rbacv1beta1.Subject
+		switch o := object.(type) {
+		case *rbacv1beta1.ClusterRoleBinding:
+			subjects = o.Subjects
+		case *rbacv1beta1.RoleBinding:
+			subjects = o.Subjects
+		}
+		for i := range subjects {
+			if subjects[i].Kind == rbacv1beta1.ServiceAccountKind {
+				subjects[i].Namespace = namespace
+			}
 		}
 	}
-	return nil
 }
 
-func createPrometheusRbac(cs *kubernetes.Clientset) error {
-	objects, err := getPrometheusRbacSpecFromFile()
-	if err != nil {
-		return err
-	}
+// applyMonitoredObjects applies objects in order, waiting for the
+// monitoring CRDs before the first object of their kind.
+func applyMonitoredObjects(ps *prometheusop.Clientset, applier *Applier, namespace string, objects []runtime.Object) error {
+	ready := map[string]bool{}
 	for _, object := range objects {
-		err := createObject(cs, object)
+		err := waitForMonitoringCRD(ps, namespace, object, ready)
 		if err != nil {
-			log.Errorf("unable to create object: %+v", object)
+			return err
+		}
+		_, err = applier.Apply(namespace, object)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func createPrometheusPersisted(ps *prometheusop.Clientset) error {
-	object, err := getPrometheusPersistedSpecFromFile()
-	if err != nil {
-		return err
-	}
-	return createPrometheusObject(ps, object)
-}
-
-func createPrometheusService(cs *kubernetes.Clientset) error {
-	svcObj, err := getPrometheusServiceSpecFromFile()
-	if err != nil {
-		return err
+// waitForMonitoringCRD waits for the CRD serving object when object is of
+// a kind registered by the prometheus operator. ready records the kinds
+// already waited for.
+func waitForMonitoringCRD(ps *prometheusop.Clientset, namespace string, object runtime.Object, ready map[string]bool) error {
+	var (
+		kind string
+		list func(opts metav1.ListOptions) (runtime.Object, error)
+	)
+	monitoring := ps.MonitoringV1()
+	switch object.(type) {
+	case *prometheusopv1.Prometheus:
+		kind, list = "Prometheus", monitoring.Prometheuses(namespace).List
+	case *prometheusopv1.ServiceMonitor:
+		kind, list = "ServiceMonitor", monitoring.ServiceMonitors(namespace).List
+	case *prometheusopv1.Alertmanager:
+		kind, list = "Alertmanager", monitoring.Alertmanagers(namespace).List
+	case *prometheusopv1.PrometheusRule:
+		kind, list = "PrometheusRule", monitoring.PrometheusRules(namespace).List
+	default:
+		return nil
 	}
-	return createObject(cs, svcObj)
-}
-
-func createObject(cs *kubernetes.Clientset, object runtime.Object) error {
-	var kobj runtime.Object
-	var err error
-	switch o := object.(type) {
-	case *extnv1beta1.Deployment:
-		kobj, err = cs.ExtensionsV1beta1().Deployments("default").Create(o)
-	case *corev1.Service:
-		kobj, err = cs.CoreV1().Services("default").Create(o)
-	case *rbacv1beta1.ClusterRoleBinding:
-		kobj, err = cs.RbacV1beta1().ClusterRoleBindings().Create(o)
-	case *rbacv1beta1.ClusterRole:
-		kobj, err = cs.RbacV1beta1().ClusterRoles().Create(o)
-	case *corev1.ServiceAccount:
-		kobj, err = cs.CoreV1().ServiceAccounts("default").Create(o)
+	if ready[kind] {
+		return nil
 	}
+	log.Infof("Waiting for %s CRD to be ready...", kind)
+	err := k8sutil.WaitForCRDReady(list)
 	if err != nil {
-		log.Errorf("unable to create prometheus operator %v", err)
 		return err
 	}
-	log.Infof("Created prometheus operator %+v", kobj)
+	ready[kind] = true
 	return nil
 }
 
-func createPrometheusObject(ps *prometheusop.Clientset, object runtime.Object) error {
-	var kobj runtime.Object
-	var err error
-	switch o := object.(type) {
-	case *prometheusopv1.Prometheus:
-		kobj, err = ps.MonitoringV1().Prometheuses("default").Create(o)
-	case *prometheusopv1.ServiceMonitor:
-		kobj, err = ps.MonitoringV1().ServiceMonitors("default").Create(o)
-	case *prometheusopv1.Alertmanager:
-		kobj, err = ps.MonitoringV1().Alertmanagers("default").Create(o)
-	}
+func getServiceMonitorsSpecFromFile(file string) ([]runtime.Object, error) {
+	data, err := assets.ReadFile(file)
 	if err != nil {
-		log.Errorf("unable to create prometheus object: %v", err)
-		return err
+		return nil, err
 	}
-	log.Infof("Created prometheus %+v", kobj)
-	return nil
+	schemaDecoder := util.NewSchemaDecoder(data)
+	return schemaDecoder.MultiDecodeInto(func() runtime.Object {
+		return &prometheusopv1.ServiceMonitor{}
+	})
 }
 
 func getPrometheusOperatorSpecFromFile() ([]runtime.Object, error) {
-	data, err := ioutil.ReadFile("k8s/monitoring/prometheus-operator.yaml")
+	data, err := assets.ReadFile("monitoring/prometheus-operator.yaml")
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +200,7 @@ func getPrometheusOperatorSpecFromFile() ([]runtime.Object, error) {
 }
 
 func getPrometheusRbacSpecFromFile() ([]runtime.Object, error) {
-	data, err := ioutil.ReadFile("k8s/monitoring/prometheus-rbac.yaml")
+	data, err := assets.ReadFile("monitoring/prometheus-rbac.yaml")
 	if err != nil {
 		return nil, err
 	}
@@ -244,18 +208,21 @@ func getPrometheusRbacSpecFromFile() ([]runtime.Object, error) {
 	return schemaDecoder.MultiDecode()
 }
 
-func getPrometheusPersistedSpecFromFile() (runtime.Object, error) {
-	data, err := ioutil.ReadFile("k8s/monitoring/prometheus-persisted.yaml")
+func getPrometheusPersistedSpecFromFile() (*prometheusopv1.Prometheus, error) {
+	data, err := assets.ReadFile("monitoring/prometheus-persisted.yaml")
 	if err != nil {
 		return nil, err
 	}
 	schemaDecoder := util.NewSchemaDecoder(data)
-	object := &prometheusopv1.Prometheus{}
-	return schemaDecoder.Decode(object)
+	object, err := schemaDecoder.Decode(&prometheusopv1.Prometheus{})
+	if err != nil {
+		return nil, err
+	}
+	return object.(*prometheusopv1.Prometheus), nil
 }
 
 func getPrometheusServiceSpecFromFile() (*corev1.Service, error) {
-	data, err := ioutil.ReadFile("k8s/monitoring/prometheus-service.yaml")
+	data, err := assets.ReadFile("monitoring/prometheus-service.yaml")
 	if err != nil {
 		return nil, err
 	}
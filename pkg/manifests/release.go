@@ -0,0 +1,118 @@
+package manifests
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/klstr/klstr/pkg/version"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extnv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ReleaseConfigMapName is the config map in the klstr namespace recording
+// the installed release.
+const ReleaseConfigMapName = "klstr-release"
+
+// Release is the klstr release installed on a cluster along with the
+// version of every installed component.
+type Release struct {
+	Version string
+	// Domain is the base domain platform UIs are published under.
+	Domain string
+	// ProxyProtocol records that the ingress load balancer passes the
+	// client addresses with the PROXY protocol.
+	ProxyProtocol bool
+	Components    map[string]string
+}
+
+// GetRelease returns the release recorded in namespace, or nil when the
+// cluster has not been adopted.
+func GetRelease(cs kubernetes.Interface, namespace string) (*Release, error) {
+	cm, err := cs.CoreV1().ConfigMaps(namespace).Get(ReleaseConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	release := &Release{
+		Version:       cm.Data["version"],
+		Domain:        cm.Data["domain"],
+		ProxyProtocol: cm.Data["proxy-protocol"] == "true",
+		Components:    map[string]string{},
+	}
+	if data, ok := cm.Data["components"]; ok {
+		err = json.Unmarshal([]byte(data), &release.Components)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+func SaveRelease(cs kubernetes.Interface, namespace string, release *Release) error {
+	components, err := json.Marshal(release.Components)
+	if err != nil {
+		return err
+	}
+	cmi := cs.CoreV1().ConfigMaps(namespace)
+	cm, err := cmi.Get(ReleaseConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = cmi.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ReleaseConfigMapName,
+				Namespace: namespace,
+			},
+			Data: map[string]string{
+				"version":        release.Version,
+				"domain":         release.Domain,
+				"proxy-protocol": strconv.FormatBool(release.ProxyProtocol),
+				"components":     string(components),
+			},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	cm = cm.DeepCopy()
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data["version"] = release.Version
+	cm.Data["domain"] = release.Domain
+	cm.Data["proxy-protocol"] = strconv.FormatBool(release.ProxyProtocol)
+	cm.Data["components"] = string(components)
+	_, err = cmi.Update(cm)
+	return err
+}
+
+// ManifestVersion returns the version of a component from its manifests,
+// which is the image tag of its first workload. Components without
+// workloads follow the klstr release.
+func ManifestVersion(objects []runtime.Object) string {
+	for _, object := range objects {
+		var containers []corev1.Container
+		switch o := object.(type) {
+		case *appsv1.Deployment:
+			containers = o.Spec.Template.Spec.Containers
+		case *appsv1.StatefulSet:
+			containers = o.Spec.Template.Spec.Containers
+		case *appsv1.DaemonSet:
+			containers = o.Spec.Template.Spec.Containers
+		case *extnv1beta1.Deployment:
+			containers = o.Spec.Template.Spec.Containers
+		case *extnv1beta1.DaemonSet:
+			containers = o.Spec.Template.Spec.Containers
+		}
+		if len(containers) > 0 {
+			return imageVersion(containers)
+		}
+	}
+	return version.Version
+}
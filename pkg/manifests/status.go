@@ -0,0 +1,126 @@
+package manifests
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VersionAnnotation records the klstr release that installed an object.
+const VersionAnnotation = "io.klstr/version"
+
+// ComponentStatus is the health of a component installed by one of the
+// installers in this package.
+type ComponentStatus struct {
+	Name      string `json:"name"`
+	Component string `json:"component"`
+	Version   string `json:"version,omitempty"`
+	Installed bool   `json:"installed"`
+	Ready     bool   `json:"ready"`
+	Message   string `json:"message,omitempty"`
+}
+
+type StatusReporter interface {
+	Status() ComponentStatus
+}
+
+func deploymentStatus(cs kubernetes.Interface, namespace, name string, status *ComponentStatus) {
+	deployment, err := cs.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		setObjectError(status, "deployment", name, err)
+		return
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status.Installed = true
+	status.Version = imageVersion(deployment.Spec.Template.Spec.Containers)
+	ds := deployment.Status
+	switch {
+	case ds.ObservedGeneration < deployment.Generation:
+		status.Message = fmt.Sprintf("deployment %s spec update has not been observed", name)
+	case ds.UpdatedReplicas < replicas:
+		status.Message = fmt.Sprintf("deployment %s has %d/%d replicas updated", name, ds.UpdatedReplicas, replicas)
+	case ds.AvailableReplicas < replicas:
+		status.Message = fmt.Sprintf("deployment %s has %d/%d replicas available", name, ds.AvailableReplicas, replicas)
+	default:
+		status.Ready = true
+	}
+}
+
+func statefulSetStatus(cs kubernetes.Interface, namespace, name string, status *ComponentStatus) {
+	statefulSet, err := cs.AppsV1().StatefulSets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		setObjectError(status, "statefulset", name, err)
+		return
+	}
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	status.Installed = true
+	status.Version = imageVersion(statefulSet.Spec.Template.Spec.Containers)
+	ss := statefulSet.Status
+	switch {
+	case ss.ObservedGeneration < statefulSet.Generation:
+		status.Message = fmt.Sprintf("statefulset %s spec update has not been observed", name)
+	case ss.UpdateRevision != "" && ss.CurrentRevision != ss.UpdateRevision:
+		status.Message = fmt.Sprintf("statefulset %s has %d/%d replicas updated", name, ss.UpdatedReplicas, replicas)
+	case ss.ReadyReplicas < replicas:
+		status.Message = fmt.Sprintf("statefulset %s has %d/%d replicas ready", name, ss.ReadyReplicas, replicas)
+	default:
+		status.Ready = true
+	}
+}
+
+func daemonSetStatus(cs kubernetes.Interface, namespace, name string, status *ComponentStatus) {
+	daemonSet, err := cs.AppsV1().DaemonSets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		setObjectError(status, "daemonset", name, err)
+		return
+	}
+	status.Installed = true
+	status.Version = imageVersion(daemonSet.Spec.Template.Spec.Containers)
+	ds := daemonSet.Status
+	switch {
+	case ds.ObservedGeneration < daemonSet.Generation:
+		status.Message = fmt.Sprintf("daemonset %s spec update has not been observed", name)
+	case ds.UpdatedNumberScheduled < ds.DesiredNumberScheduled:
+		status.Message = fmt.Sprintf("daemonset %s has %d/%d pods updated", name, ds.UpdatedNumberScheduled, ds.DesiredNumberScheduled)
+	case ds.NumberAvailable < ds.DesiredNumberScheduled:
+		status.Message = fmt.Sprintf("daemonset %s has %d/%d pods available", name, ds.NumberAvailable, ds.DesiredNumberScheduled)
+	default:
+		status.Ready = true
+	}
+}
+
+// setObjectError marks the component as not installed when the object is
+// missing, and as installed but not ready when it cannot be read.
+func setObjectError(status *ComponentStatus, kind, name string, err error) {
+	status.Ready = false
+	if errors.IsNotFound(err) {
+		status.Installed = false
+		status.Message = fmt.Sprintf("%s %s is not installed", kind, name)
+		return
+	}
+	status.Installed = true
+	status.Message = fmt.Sprintf("unable to get %s %s: %v", kind, name, err)
+}
+
+// imageVersion returns the tag of the image run by the first container.
+func imageVersion(containers []corev1.Container) string {
+	if len(containers) == 0 {
+		return ""
+	}
+	image := containers[0].Image
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "latest"
+	}
+	return image[i+1:]
+}
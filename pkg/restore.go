@@ -0,0 +1,218 @@
+package klstr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klstr/klstr/pkg/command_jobs"
+	"github.com/klstr/klstr/pkg/util"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// uploadsKey is the key below the store prefix the dump files restored
+// from the local disk are staged under.
+const uploadsKey = "uploads"
+
+// RestoreOptions name the dump to restore, a backup in the backup store or
+// a local dump file.
+type RestoreOptions struct {
+	Backup   string
+	DumpFile string
+}
+
+// RestoreDB restores a dump into the database of dc, which is created when
+// it does not exist.
+func RestoreDB(dc *DatabaseConfig, ro RestoreOptions, kubeconfig string) error {
+	config, err := util.NewClientConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+	cs, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	dj := DatabaseJob{
+		cs: cs,
+		dc: dc,
+	}
+	return dj.RestoreDBJob(ro, config)
+}
+
+// RestoreDBJob restores with a job. config is used to stream local dump
+// files into the cluster.
+func (dj *DatabaseJob) RestoreDBJob(ro RestoreOptions, config *rest.Config) error {
+	if (ro.Backup == "") == (ro.DumpFile == "") {
+		return errors.New("restore needs either a backup or a dump file")
+	}
+	dbiSecretName := fmt.Sprintf("dbi-%s-%s", dj.dc.DBType, dj.dc.DBIName)
+	_, err := dj.cs.CoreV1().Secrets(dj.dc.Namespace).Get(dbiSecretName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return fmt.Errorf("db instance %s of type %s is not registered", dj.dc.DBIName, dj.dc.DBType)
+	}
+	if err != nil {
+		return err
+	}
+	source := &command_jobs.RestoreSource{}
+	if ro.Backup != "" {
+		source, err = dj.backupRestoreSource(ro.Backup)
+		if err != nil {
+			return err
+		}
+	}
+	if dj.dc.DBName == "" {
+		return errors.New("no database to restore into given")
+	}
+	if ro.DumpFile != "" {
+		source, err = dj.stageDumpFile(config, ro.DumpFile)
+		if err != nil {
+			return err
+		}
+	}
+	if source.Archive && dj.dc.DBType != "pg" {
+		return fmt.Errorf("pg_dump archives cannot be restored into a %s instance", dj.dc.DBType)
+	}
+	jobobj, err := command_jobs.NewJobFromTemplate()
+	if err != nil {
+		return err
+	}
+	cj, err := command_jobs.CreateCommandJob(dj.dc.DBType, command_jobs.CommandJobOptions{
+		DBName:  dj.dc.DBName,
+		DBIName: dj.dc.DBIName,
+		Restore: source,
+	})
+	if err != nil {
+		return err
+	}
+	cj.BuildRestoreCommand(jobobj)
+	_, err = dj.runJob(jobobj, "restore", os.Stdout)
+	return err
+}
+
+// backupRestoreSource reads the backup from the store. The database the
+// backup was taken from is restored into unless another one is given.
+func (dj *DatabaseJob) backupRestoreSource(id string) (*command_jobs.RestoreSource, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid backup id %s, klstr database backups list shows the backups", id)
+	}
+	if parts[0] != dj.dc.DBType {
+		return nil, fmt.Errorf("backup %s is a %s dump and cannot be restored into a %s instance", id, parts[0], dj.dc.DBType)
+	}
+	err := dj.checkBackupStore()
+	if err != nil {
+		return nil, err
+	}
+	if dj.dc.DBName == "" {
+		dj.dc.DBName = parts[2]
+	}
+	return &command_jobs.RestoreSource{
+		Backup:  id,
+		Gzip:    strings.HasSuffix(id, ".gz"),
+		Archive: strings.HasSuffix(id, ".dump"),
+	}, nil
+}
+
+// stageDumpFile uploads the dump file to the backup store, where the
+// restore job reads it and removes it once done. The file is streamed
+// through the api server into a job running the minio client, as the store
+// is usually only reachable from within the cluster. The format of the
+// dump is told by its first bytes.
+func (dj *DatabaseJob) stageDumpFile(config *rest.Config, path string) (*command_jobs.RestoreSource, error) {
+	err := dj.checkBackupStore()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("dump file %s is empty", path)
+	}
+	source, err := command_jobs.DetectDumpFormat(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dump file %s %v", path, err)
+	}
+	if source.Archive && dj.dc.DBType != "pg" {
+		return nil, fmt.Errorf("pg_dump archives cannot be restored into a %s instance", dj.dc.DBType)
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	source.Backup = fmt.Sprintf("%s/restore-%d", uploadsKey, time.Now().Unix())
+	source.Staged = true
+	job, err := command_jobs.NewStageJob(source.Backup)
+	if err != nil {
+		return nil, err
+	}
+	job, deadline, err := dj.createJob(job, "stage")
+	if err != nil {
+		return nil, err
+	}
+	pod, err := dj.waitForJobPod(job, time.Until(deadline))
+	if err != nil {
+		return nil, err
+	}
+	if pod == nil {
+		return nil, fmt.Errorf("job %s/%s finished before the dump file was sent", job.Namespace, job.Name)
+	}
+	fmt.Printf("uploading %s to the backup store through job %s/%s\n", path, job.Namespace, job.Name)
+	err = dj.attachStdin(config, pod, file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to upload dump file %s %v", path, err)
+	}
+	job, err = dj.waitForJob(job, time.Until(deadline))
+	if err != nil {
+		return nil, err
+	}
+	if failed, message := jobFailed(job); failed {
+		fmt.Printf("job %s/%s kept for inspection\n", job.Namespace, job.Name)
+		return nil, fmt.Errorf("unable to upload dump file %s: %s", path, message)
+	}
+	err = dj.cs.BatchV1().Jobs(job.Namespace).Delete(job.Name, backgroundDeletion())
+	if err != nil {
+		log.Warnf("unable to remove job %s/%s %v", job.Namespace, job.Name, err)
+	}
+	return source, nil
+}
+
+// attachStdin streams r to the stdin of the container of pod, until the
+// container exits.
+func (dj *DatabaseJob) attachStdin(config *rest.Config, pod *corev1.Pod, r io.Reader) error {
+	req := dj.cs.CoreV1().RESTClient().Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource("attach").
+		VersionedParams(&corev1.PodAttachOptions{
+			Container: pod.Spec.Containers[0].Name,
+			Stdin:     true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+	return executor.Stream(remotecommand.StreamOptions{
+		Stdin:  r,
+		Stderr: os.Stderr,
+	})
+}
@@ -0,0 +1,103 @@
+package klstr
+
+import (
+	prometheusop "github.com/coreos/prometheus-operator/pkg/client/monitoring"
+	prometheusopv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/klstr/klstr/pkg/manifests"
+	"github.com/klstr/klstr/pkg/util"
+	"github.com/klstr/klstr/pkg/version"
+	apiextnclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/kubernetes"
+)
+
+type StatusOptions struct {
+	KubeConfig string
+	Namespace  string
+}
+
+// ClusterStatus describes the klstr release running on a cluster and the
+// health of its components. Components skipped when the cluster was adopted
+// are reported as not installed and do not make the cluster unhealthy. A
+// cluster without any component installed is not adopted, and unhealthy.
+type ClusterStatus struct {
+	ClientVersion string                      `json:"clientVersion"`
+	ServerVersion string                      `json:"serverVersion"`
+	Adopted       bool                        `json:"adopted"`
+	Healthy       bool                        `json:"healthy"`
+	Components    []manifests.ComponentStatus `json:"components"`
+}
+
+type StatusChecker struct {
+	options manifests.ComponentOptions
+}
+
+func NewStatusChecker(so StatusOptions) (*StatusChecker, error) {
+	if so.Namespace == "" {
+		so.Namespace = util.DefaultNamespace
+	}
+	config, err := util.NewClientConfig(so.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	pclientSet, err := prometheusop.NewForConfig(
+		&prometheusopv1.DefaultCrdKinds,
+		"monitoring.coreos.com",
+		config,
+	)
+	if err != nil {
+		return nil, err
+	}
+	eclientSet, err := apiextnclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &StatusChecker{
+		options: manifests.ComponentOptions{
+			Namespace:        so.Namespace,
+			KubeClient:       clientSet,
+			PrometheusClient: pclientSet,
+			ExtensionsClient: eclientSet,
+		},
+	}, nil
+}
+
+// Status checks every component. The server version is the release
+// recorded on the muservice crd when the cluster was adopted.
+func (sc *StatusChecker) Status() (*ClusterStatus, error) {
+	components, err := manifests.ResolveComponents(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	status := &ClusterStatus{
+		ClientVersion: version.Version,
+		Healthy:       true,
+	}
+	for _, component := range components {
+		reporter, ok := component.Installer(sc.options).(manifests.StatusReporter)
+		if !ok {
+			continue
+		}
+		cs := reporter.Status()
+		if component.Group == manifests.GroupCore {
+			status.ServerVersion = cs.Version
+		}
+		if cs.Installed {
+			status.Adopted = true
+		}
+		if cs.Installed && !cs.Ready {
+			status.Healthy = false
+		}
+		status.Components = append(status.Components, cs)
+	}
+	if !status.Adopted {
+		status.Healthy = false
+	}
+	if status.ServerVersion == "" {
+		status.ServerVersion = "unknown"
+	}
+	return status, nil
+}
@@ -0,0 +1,221 @@
+package klstr
+
+import (
+	"fmt"
+
+	"github.com/klstr/klstr/pkg/manifests"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// UnadoptPlan returns the installed components in the order unadopt
+// removes them, which is the reverse of their install order.
+func (a *Adopter) UnadoptPlan() ([]manifests.Component, error) {
+	release, err := manifests.GetRelease(a.clientSet, a.ao.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	components, err := manifests.ResolveComponents(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var plan []manifests.Component
+	for i := len(components) - 1; i >= 0; i-- {
+		if release != nil {
+			if _, ok := release.Components[components[i].Name]; !ok {
+				continue
+			}
+		}
+		plan = append(plan, components[i])
+	}
+	return plan, nil
+}
+
+// UnadoptCluster removes the objects klstr installed, component by
+// component. The persistent volume claims of the statefulsets of removed
+// components are removed as well unless KeepData is set.
+func (a *Adopter) UnadoptCluster() error {
+	plan, err := a.UnadoptPlan()
+	if err != nil {
+		return err
+	}
+	release, err := manifests.GetRelease(a.clientSet, a.ao.Namespace)
+	if err != nil {
+		return err
+	}
+	err = a.resolveDomain(release)
+	if err != nil {
+		return err
+	}
+	options := a.componentOptions()
+	var results []ComponentResult
+	for _, component := range plan {
+		result := a.removeComponent(component, options)
+		if release != nil {
+			result.Version = release.Components[component.Name]
+		}
+		results = append(results, result)
+	}
+	printComponentResults(results)
+	err = a.forgetRemovedComponents(release, results)
+	if err != nil {
+		return err
+	}
+	return componentErrors("remove", results)
+}
+
+func (a *Adopter) removeComponent(component manifests.Component, options manifests.ComponentOptions) ComponentResult {
+	result := ComponentResult{Name: component.Name}
+	if component.Name == manifests.MuserviceCRDComponent {
+		// Deleting the crd deletes every Muservice along with the
+		// deployments they own.
+		muservices, err := a.kclientSet.KlstrV1().Muservices(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			result.Result = ComponentFailed
+			result.Err = err
+			return result
+		}
+		if err == nil && len(muservices.Items) > 0 {
+			result.Result = ComponentSkipped
+			result.Err = fmt.Errorf("%d muservices still exist, delete them first", len(muservices.Items))
+			return result
+		}
+	}
+	log.Infof("Removing component %s", component.Name)
+	objects, err := component.Installer(options).Objects()
+	if err != nil {
+		result.Result = ComponentFailed
+		result.Err = err
+		return result
+	}
+	var claimSelectors []labels.Selector
+	if !a.ao.KeepData {
+		claimSelectors, err = a.claimSelectors(objects)
+		if err != nil {
+			result.Result = ComponentFailed
+			result.Err = err
+			return result
+		}
+	}
+	for i := len(objects) - 1; i >= 0; i-- {
+		deleted, err := a.applier.Delete(a.ao.Namespace, objects[i])
+		if err != nil {
+			log.Errorf("Unable to remove %s - %s", component.Name, err)
+			result.Result = ComponentFailed
+			result.Err = err
+			return result
+		}
+		if deleted == manifests.DeleteUnmanaged {
+			result.Kept = append(result.Kept, objectName(objects[i]))
+		}
+	}
+	result.Result = ComponentRemoved
+	if len(result.Kept) > 0 {
+		// the claims may still be in use by the objects left in place
+		return result
+	}
+	err = a.deleteClaims(claimSelectors)
+	if err != nil {
+		result.Result = ComponentFailed
+		result.Err = err
+	}
+	return result
+}
+
+// claimSelectors returns the selectors of the claims created from the
+// volume claim templates of the statefulsets among objects, or owned by
+// one of objects such as the statefulsets prometheus-operator runs. Only
+// the statefulsets managed by klstr, or owned by an object managed by
+// klstr, are considered. The selectors have to be collected before the
+// statefulsets are deleted.
+func (a *Adopter) claimSelectors(objects []runtime.Object) ([]labels.Selector, error) {
+	names := map[string]bool{}
+	for _, object := range objects {
+		names[objectName(object)] = true
+	}
+	statefulSets, err := a.clientSet.AppsV1().StatefulSets(a.ao.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var selectors []labels.Selector
+	for _, ss := range statefulSets.Items {
+		if len(ss.Spec.VolumeClaimTemplates) == 0 || ss.Spec.Selector == nil {
+			continue
+		}
+		owned := names["StatefulSet/"+ss.Name] && ss.Labels[manifests.ManagedByLabel] == manifests.ManagedByKlstr
+		for _, owner := range ss.OwnerReferences {
+			owned = owned || names[owner.Kind+"/"+owner.Name]
+		}
+		if !owned {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(ss.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+func (a *Adopter) deleteClaims(selectors []labels.Selector) error {
+	if len(selectors) == 0 {
+		return nil
+	}
+	pvci := a.clientSet.CoreV1().PersistentVolumeClaims(a.ao.Namespace)
+	claims, err := pvci.List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, claim := range claims.Items {
+		for _, selector := range selectors {
+			if !selector.Matches(labels.Set(claim.Labels)) {
+				continue
+			}
+			err = pvci.Delete(claim.Name, &metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			log.Infof("PersistentVolumeClaim %s deleted", claim.Name)
+			break
+		}
+	}
+	return nil
+}
+
+// forgetRemovedComponents drops the components removed entirely from the
+// release, and the release itself once every component is gone. Components
+// with objects left in place stay in the release.
+func (a *Adopter) forgetRemovedComponents(release *manifests.Release, results []ComponentResult) error {
+	if release == nil {
+		return nil
+	}
+	for _, result := range results {
+		if result.Err == nil && len(result.Kept) == 0 {
+			delete(release.Components, result.Name)
+		}
+	}
+	if len(release.Components) > 0 {
+		return manifests.SaveRelease(a.clientSet, a.ao.Namespace, release)
+	}
+	err := a.clientSet.CoreV1().ConfigMaps(a.ao.Namespace).Delete(manifests.ReleaseConfigMapName, &metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// objectName names object by its kind and name, such as
+// StatefulSet/prometheus.
+func objectName(object runtime.Object) string {
+	name := ""
+	accessor, err := meta.Accessor(object)
+	if err == nil {
+		name = accessor.GetName()
+	}
+	return object.GetObjectKind().GroupVersionKind().Kind + "/" + name
+}
@@ -0,0 +1,180 @@
+package klstr
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/klstr/klstr/pkg/manifests"
+	"github.com/klstr/klstr/pkg/version"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// UpgradeCluster moves the components recorded in the release of the
+// cluster to the manifests bundled with this klstr release, one component
+// at a time. A component that does not become ready is rolled back to the
+// configuration it was last applied with.
+func (a *Adopter) UpgradeCluster() error {
+	release, err := manifests.GetRelease(a.clientSet, a.ao.Namespace)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return fmt.Errorf("no klstr release found in namespace %s, run klstr adopt first", a.ao.Namespace)
+	}
+	err = a.resolveDomain(release)
+	if err != nil {
+		return err
+	}
+	skip := append([]string{}, a.ao.Skip...)
+	if a.ao.SkipLogging {
+		skip = append(skip, manifests.GroupLogging)
+	}
+	if a.ao.SkipMetrics {
+		skip = append(skip, manifests.GroupMetrics)
+	}
+	resolved, err := manifests.ResolveComponents(a.ao.Components, skip)
+	if err != nil {
+		return err
+	}
+	options := a.componentOptions()
+	var components []manifests.Component
+	installers := map[string]manifests.ServiceInstaller{}
+	versions := map[string]string{}
+	fmt.Printf("klstr %s -> %s\n", release.Version, version.Version)
+	for _, component := range resolved {
+		installed, ok := release.Components[component.Name]
+		if !ok {
+			fmt.Printf("- %s is not installed, run klstr adopt --components=%s to install it\n", component.Name, component.Name)
+			continue
+		}
+		installer := component.Installer(options)
+		objects, err := installer.Objects()
+		if err != nil {
+			return err
+		}
+		versions[component.Name] = manifests.ManifestVersion(objects)
+		installers[component.Name] = installer
+		components = append(components, component)
+		if installed == versions[component.Name] {
+			fmt.Printf("- %s %s\n", component.Name, installed)
+			continue
+		}
+		fmt.Printf("- %s %s -> %s\n", component.Name, installed, versions[component.Name])
+	}
+
+	results := runComponents(components, func(component manifests.Component) ComponentResult {
+		result := a.upgradeComponent(component.Name, installers[component.Name])
+		result.Version = versions[component.Name]
+		if result.Result == ComponentRolledBack {
+			result.Version = release.Components[component.Name]
+		}
+		return result
+	})
+	printComponentResults(results)
+	err = a.saveUpgradedRelease(release, results)
+	if err != nil {
+		return err
+	}
+	return componentErrors("upgrade", results)
+}
+
+func (a *Adopter) upgradeComponent(name string, installer manifests.ServiceInstaller) ComponentResult {
+	result := ComponentResult{Name: name}
+	objects, err := installer.Objects()
+	if err != nil {
+		result.Result = ComponentFailed
+		result.Err = err
+		return result
+	}
+	previous := make([]*unstructured.Unstructured, len(objects))
+	for i, object := range objects {
+		previous[i], err = a.applier.LastApplied(a.ao.Namespace, object)
+		if err != nil {
+			result.Result = ComponentFailed
+			result.Err = err
+			return result
+		}
+	}
+	log.Infof("Upgrading component %s", name)
+	err = installer.InstallService()
+	if err == nil {
+		err = a.waitForComponent(installer)
+	}
+	if err == nil {
+		result.Result = ComponentUpgraded
+		return result
+	}
+	log.Errorf("Unable to upgrade %s - %s, rolling back", name, err)
+	rollbackErr := a.rollbackComponent(objects, previous)
+	if rollbackErr != nil {
+		result.Result = ComponentFailed
+		result.Err = fmt.Errorf("%v, rollback failed: %v", err, rollbackErr)
+		return result
+	}
+	result.Result = ComponentRolledBack
+	result.Err = err
+	return result
+}
+
+// rollbackComponent re-applies the previous configuration of objects, in
+// reverse order, and deletes the objects the upgrade created.
+func (a *Adopter) rollbackComponent(objects []runtime.Object, previous []*unstructured.Unstructured) error {
+	for i := len(objects) - 1; i >= 0; i-- {
+		var err error
+		if previous[i] == nil {
+			_, err = a.applier.Delete(a.ao.Namespace, objects[i])
+		} else {
+			_, err = a.applier.Apply(a.ao.Namespace, previous[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Adopter) waitForComponent(installer manifests.ServiceInstaller) error {
+	reporter, ok := installer.(manifests.StatusReporter)
+	if !ok {
+		return nil
+	}
+	timeout := a.ao.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+	var status manifests.ComponentStatus
+	err := wait.PollImmediate(5*time.Second, timeout, func() (bool, error) {
+		status = reporter.Status()
+		if !status.Ready {
+			log.Infof("Waiting for %s: %s", status.Component, status.Message)
+		}
+		return status.Ready, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return errors.New(status.Message)
+	}
+	return err
+}
+
+// saveUpgradedRelease records the new version of upgraded components. The
+// release version only moves once every component is upgraded.
+func (a *Adopter) saveUpgradedRelease(release *manifests.Release, results []ComponentResult) error {
+	upgraded := true
+	for _, result := range results {
+		if result.Err != nil {
+			upgraded = false
+			continue
+		}
+		release.Components[result.Name] = result.Version
+	}
+	if upgraded {
+		release.Version = version.Version
+	}
+	release.Domain = a.ao.Domain
+	release.ProxyProtocol = a.ao.ProxyProtocol != nil && *a.ao.ProxyProtocol
+	return manifests.SaveRelease(a.clientSet, a.ao.Namespace, release)
+}
@@ -0,0 +1,27 @@
+package util
+
+const (
+	// DatabaseLabel and DBInstanceLabel mark the objects belonging to a
+	// database, such as its credentials and jobs, with the database and
+	// the db instance holding it.
+	DatabaseLabel   = "io.klstr/database"
+	DBInstanceLabel = "io.klstr/db-instance"
+)
+
+// DatabaseType is a type of database klstr provisions.
+type DatabaseType struct {
+	// CommandJob is the command_jobs type running the database jobs.
+	CommandJob string
+	// EnvPrefix prefixes the environment variables of the credentials.
+	EnvPrefix string
+	// Scheme is the scheme of the connection URIs.
+	Scheme string
+}
+
+// DatabaseTypes maps the names of the database types, along with their
+// aliases, to the types.
+var DatabaseTypes = map[string]DatabaseType{
+	"pg":       {CommandJob: "pg", EnvPrefix: "PG", Scheme: "postgres"},
+	"postgres": {CommandJob: "pg", EnvPrefix: "PG", Scheme: "postgres"},
+	"mysql":    {CommandJob: "mysql", EnvPrefix: "MYSQL", Scheme: "mysql"},
+}
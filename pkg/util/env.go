@@ -0,0 +1,10 @@
+package util
+
+import "strings"
+
+// EnvVarName joins parts into an environment variable name such as
+// SAMPLECACHE_REDIS_HOST.
+func EnvVarName(parts ...string) string {
+	name := strings.ToUpper(strings.Join(parts, "_"))
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
@@ -1,17 +1,14 @@
 package util
 
 import (
-	"errors"
-
+	clientset "github.com/klstr/klstr/pkg/client/clientset/versioned"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 func NewKubeClient(kubeconfig string) (*kubernetes.Clientset, error) {
-	if kubeconfig == "" {
-		return nil, errors.New("Kubeconfig is empty")
-	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	config, err := NewClientConfig(kubeconfig)
 	if err != nil {
 		return nil, err
 	}
@@ -21,3 +18,47 @@ func NewKubeClient(kubeconfig string) (*kubernetes.Clientset, error) {
 	}
 	return cs, nil
 }
+
+func NewKlstrClient(kubeconfig string) (*clientset.Clientset, error) {
+	config, err := NewClientConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	kcs, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return kcs, nil
+}
+
+// kubeContext overrides the current context of kubeconfigs when set.
+var kubeContext string
+
+// SetKubeContext makes clients use context instead of the current context
+// of their kubeconfig.
+func SetKubeContext(context string) {
+	kubeContext = context
+}
+
+// NewClientConfig returns the client config for kubeconfig. An empty
+// kubeconfig falls back to $KUBECONFIG and then ~/.kube/config.
+func NewClientConfig(kubeconfig string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// CurrentKubeContext returns the context clients of kubeconfig use.
+func CurrentKubeContext(kubeconfig string) (string, error) {
+	if kubeContext != "" {
+		return kubeContext, nil
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	config, err := rules.Load()
+	if err != nil {
+		return "", err
+	}
+	return config.CurrentContext, nil
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultNamespace is where the klstr platform components, db instances
+// and database jobs live, away from application workloads.
+const DefaultNamespace = "klstr-system"
+
+// LegacyNamespace is the klstr namespace of the installs predating
+// DefaultNamespace.
+const LegacyNamespace = "klstr"
+
+func EnsureNamespace(cs kubernetes.Interface, name string) error {
+	_, err := cs.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	ns, err := cs.CoreV1().Namespaces().Create(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	if err == nil {
+		log.Infof("Created namespace %s", ns.Name)
+	}
+	return nil
+}
+
+// ResolveNamespace falls back to LegacyNamespace when namespace is the
+// DefaultNamespace, the legacy namespace exists and no db instances are
+// registered in the default one. Older installs keep finding their db
+// instances and database jobs that way. Db instances are told by their
+// label, which registrations predating DefaultNamespace lack.
+func ResolveNamespace(cs kubernetes.Interface, namespace string) (string, error) {
+	if namespace != DefaultNamespace {
+		return namespace, nil
+	}
+	_, err := cs.CoreV1().Namespaces().Get(LegacyNamespace, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return namespace, nil
+	}
+	if err != nil {
+		return namespace, err
+	}
+	secrets, err := cs.CoreV1().Secrets(DefaultNamespace).List(metav1.ListOptions{
+		LabelSelector: DBInstanceLabel,
+		Limit:         1,
+	})
+	if err != nil {
+		return namespace, err
+	}
+	if len(secrets.Items) > 0 {
+		return namespace, nil
+	}
+	log.Warnf("using namespace %s of an older klstr install, pass --klstr-namespace=%s to silence this", LegacyNamespace, LegacyNamespace)
+	return LegacyNamespace, nil
+}
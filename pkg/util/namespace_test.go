@@ -0,0 +1,49 @@
+package util
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestResolveNamespace(t *testing.T) {
+	namespace := func(name string) runtime.Object {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	secret := func(namespace, name string) runtime.Object {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+	dbInstance := func(namespace, name string) runtime.Object {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "dbi-pg-" + name,
+			Labels:    map[string]string{DBInstanceLabel: name},
+		}}
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		objects   []runtime.Object
+		expected  string
+	}{
+		{"fresh install", DefaultNamespace, nil, DefaultNamespace},
+		{"legacy install", DefaultNamespace, []runtime.Object{namespace(LegacyNamespace), secret(LegacyNamespace, "dbi-pg-dev")}, LegacyNamespace},
+		{"legacy install with the new namespace", DefaultNamespace, []runtime.Object{namespace(LegacyNamespace), namespace(DefaultNamespace), secret(LegacyNamespace, "dbi-pg-dev")}, LegacyNamespace},
+		{"migrated install", DefaultNamespace, []runtime.Object{namespace(LegacyNamespace), dbInstance(DefaultNamespace, "dev"), secret(LegacyNamespace, "dbi-pg-dev")}, DefaultNamespace},
+		{"unlabelled secrets in the new namespace", DefaultNamespace, []runtime.Object{namespace(LegacyNamespace), secret(DefaultNamespace, "other")}, LegacyNamespace},
+		{"namespace picked", "platform", []runtime.Object{namespace(LegacyNamespace), secret(LegacyNamespace, "dbi-pg-dev")}, "platform"},
+	}
+	for _, test := range tests {
+		cs := fake.NewSimpleClientset(test.objects...)
+		resolved, err := ResolveNamespace(cs, test.namespace)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if resolved != test.expected {
+			t.Errorf("%s: expected namespace %s, got %s", test.name, test.expected, resolved)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// GeneratePassword returns a random alphanumeric password of the given
+// length. Only alphanumeric characters are used so that the password can
+// be embedded in SQL statements and connection URIs without escaping.
+func GeneratePassword(length int) (string, error) {
+	password := make([]byte, length)
+	max := big.NewInt(int64(len(passwordChars)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordChars[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := GeneratePassword(24)
+	if err != nil {
+		t.Fatal("error generating password ", err)
+	}
+	if len(password) != 24 {
+		t.Errorf("expected password of length 24, got %d", len(password))
+	}
+	for _, c := range password {
+		if !strings.ContainsRune(passwordChars, c) {
+			t.Errorf("unexpected character %q in password", c)
+		}
+	}
+	other, _ := GeneratePassword(24)
+	if password == other {
+		t.Error("generated the same password twice")
+	}
+}
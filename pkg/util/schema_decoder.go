@@ -4,12 +4,17 @@ import (
 	"bufio"
 	"bytes"
 
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
+func init() {
+	klstrv1.AddToScheme(scheme.Scheme)
+}
+
 type SchemaDecoder struct {
 	data []byte
 }
@@ -29,6 +34,13 @@ func (sc *SchemaDecoder) Decode(objects ...runtime.Object) (runtime.Object, erro
 }
 
 func (sc *SchemaDecoder) MultiDecode() ([]runtime.Object, error) {
+	return sc.MultiDecodeInto(nil)
+}
+
+// MultiDecodeInto decodes every document into an object returned by
+// newObject, for kinds that are not registered with the scheme. A nil
+// newObject decodes registered kinds.
+func (sc *SchemaDecoder) MultiDecodeInto(newObject func() runtime.Object) ([]runtime.Object, error) {
 	decoder := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(sc.data)))
 	var objects []runtime.Object
 	for {
@@ -40,8 +52,12 @@ func (sc *SchemaDecoder) MultiDecode() ([]runtime.Object, error) {
 			log.Error("error reading from yaml ", err)
 			return nil, err
 		}
+		var into []runtime.Object
+		if newObject != nil {
+			into = append(into, newObject())
+		}
 		sd := NewSchemaDecoder(obj)
-		object, err := sd.Decode()
+		object, err := sd.Decode(into...)
 		if err != nil {
 			log.Error("error decoding multi yaml ", err)
 			return nil, err
@@ -4,8 +4,10 @@ import (
 	"testing"
 
 	prometheusopv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	klstrv1 "github.com/klstr/klstr/pkg/apis/klstr/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestDecodeWithNoArg(t *testing.T) {
@@ -104,6 +106,78 @@ spec:
 	}
 }
 
+func TestDecodeMuservice(t *testing.T) {
+	muserviceYaml := `
+apiVersion: io.klstr/v1
+kind: Muservice
+metadata:
+  name: muservice
+spec:
+  image: quay.io/klstr/muservice:v0.1.0
+  replicas: 3
+  ports:
+  - port: 4323
+  expose: muservice.minikube.local
+`
+	sd := NewSchemaDecoder([]byte(muserviceYaml))
+	obj, err := sd.Decode()
+	if err != nil {
+		t.Error("error decoding ", err)
+	}
+	mu, ok := obj.(*klstrv1.Muservice)
+	if !ok {
+		t.Fatal("object is of wrong type")
+	}
+	if *mu.Spec.Replicas != 3 || mu.Spec.Expose[0].Host != "muservice.minikube.local" {
+		t.Errorf("muservice spec not decoded correctly: %+v", mu.Spec)
+	}
+}
+
+func TestMultiDecodeInto(t *testing.T) {
+	monitorsYaml := `
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: kubelet
+spec:
+  selector:
+    matchLabels:
+      k8s-app: kubelet
+  endpoints:
+  - port: https-metrics
+---
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: apiserver
+spec:
+  selector:
+    matchLabels:
+      component: apiserver
+  endpoints:
+  - port: https
+`
+	sd := NewSchemaDecoder([]byte(monitorsYaml))
+	objs, err := sd.MultiDecodeInto(func() runtime.Object {
+		return &prometheusopv1.ServiceMonitor{}
+	})
+	if err != nil {
+		t.Fatal("error decoding multi yaml ", err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 service monitors, got %d", len(objs))
+	}
+	for i, name := range []string{"kubelet", "apiserver"} {
+		monitor, ok := objs[i].(*prometheusopv1.ServiceMonitor)
+		if !ok {
+			t.Fatalf("object %d is not a service monitor", i)
+		}
+		if monitor.Name != name {
+			t.Errorf("expected service monitor %s, got %s", name, monitor.Name)
+		}
+	}
+}
+
 func testMultiDecode(t *testing.T) {
 	multiYaml := `
 apiVersion: apps/v1
@@ -0,0 +1,5 @@
+package version
+
+// Version is the klstr release, overridden at build time with
+// -ldflags "-X github.com/klstr/klstr/pkg/version.Version=<version>".
+var Version = "v0.1.0-dev"